@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+	yaml12 "gopkg.in/yaml.v3"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// backend is a Terraform resource type that can create Kubernetes objects
+type backend struct {
+	// name selects the backend with --backend
+	name string
+
+	// resourceType is the type of Terraform resource
+	resourceType string
+
+	// provider is the local name of the provider, source and version
+	// are what versions.tf requires by default
+	provider string
+	source   string
+	version  string
+
+	// inlineProviderAttrs are added to provider blocks that don't
+	// read the kubeconfig file
+	inlineProviderAttrs [][2]string
+
+	// output is the attribute of each resource returned by outputs.tf
+	// and outputDescription describes it
+	output            string
+	outputDescription string
+
+	// attribute is the argument of the resource that holds the manifest,
+	// as an object or as a YAML string when yaml is set
+	attribute string
+	yaml      bool
+
+	// body formats the attribute that holds the manifest
+	body func(r resource, o *options) string
+
+	// importID returns the id to import an object by, nil if
+	// the resource type can't be imported
+	importID func(doc cty.Value, namespace string) (importID, bool)
+}
+
+var (
+	// backendManifest is kubernetes_manifest from the official provider
+	backendManifest = backend{
+		name:              "manifest",
+		resourceType:      "kubernetes_manifest",
+		provider:          "kubernetes",
+		source:            "hashicorp/kubernetes",
+		version:           defaultProviderVersion,
+		output:            "object",
+		outputDescription: "The objects as they exist in the cluster, by resource name",
+		attribute:         "manifest",
+		body:              manifestBody,
+		importID:          resourceImportID,
+	}
+
+	// backendKubectl is kubectl_manifest from the kubectl provider,
+	// which does not need CRDs to exist at plan time
+	backendKubectl = backend{
+		name:                "kubectl",
+		resourceType:        "kubectl_manifest",
+		provider:            "kubectl",
+		source:              "alekc/kubectl",
+		version:             ">= 2.0.0",
+		inlineProviderAttrs: [][2]string{{"load_config_file", "false"}},
+		output:              "uid",
+		outputDescription:   "The UIDs of the objects, by resource name",
+		attribute:           "yaml_body",
+		yaml:                true,
+		body:                yamlBody,
+		importID:            kubectlImportID,
+	}
+
+	// backendK8s is k8s_manifest from the banzaicloud k8s provider
+	backendK8s = backend{
+		name:                "k8s",
+		resourceType:        "k8s_manifest",
+		provider:            "k8s",
+		source:              "banzaicloud/k8s",
+		version:             ">= 0.9.0",
+		inlineProviderAttrs: [][2]string{{"load_config_file", "false"}},
+		output:              "id",
+		outputDescription:   "The ids of the objects, by resource name",
+		attribute:           "content",
+		yaml:                true,
+		body:                yamlBody,
+	}
+)
+
+// backends are all of the backends by name
+var backends = map[string]backend{
+	backendManifest.name: backendManifest,
+	backendKubectl.name:  backendKubectl,
+	backendK8s.name:      backendK8s,
+}
+
+// parseBackend returns the backend with the name
+func parseBackend(s string) (backend, error) {
+	if b, ok := backends[s]; ok {
+		return b, nil
+	}
+	names := []string{}
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return backend{}, fmt.Errorf("unknown backend %q, must be one of: %s", s, strings.Join(names, ", "))
+}
+
+// manifestDecodeFunction is the function of the kubernetes provider
+// that decodes a YAML manifest
+const manifestDecodeFunction = "provider::kubernetes::manifest_decode"
+
+// decodeFunctions are the functions --manifest-decode writes
+var decodeFunctions = []string{manifestDecodeFunction, "yamldecode"}
+
+// decodeFunction returns the function that --manifest-decode writes,
+// which is yamldecode for targets without provider-defined functions
+func (o *options) decodeFunction() string {
+	if o.target.supports(featureProviderFunctions) {
+		return manifestDecodeFunction
+	}
+	return "yamldecode"
+}
+
+// manifestBody writes the manifest as an HCL object, or as YAML passed
+// to the decode function with --manifest-decode. Documents holding
+// Terraform expressions are always written as an HCL object.
+func manifestBody(r resource, o *options) string {
+	v := r.doc
+	if s, ok := yamlDocument(r.doc); ok && o.manifestDecode {
+		v = terraform.FunctionCallVal(o.decodeFunction(), cty.StringVal(s))
+	}
+	return fmt.Sprintf("  %s = %s\n", o.backend.attribute, terraform.FormatValueWithOptions(v, 2, o.formatOptions()))
+}
+
+// yamlBody writes the manifest as a YAML string. Documents holding
+// Terraform expressions are written as a yamlencode() call instead,
+// so that the expressions are evaluated.
+func yamlBody(r resource, o *options) string {
+	v := terraform.FunctionCallVal("yamlencode", r.doc)
+	if s, ok := yamlDocument(r.doc); ok {
+		v = cty.StringVal(s)
+	}
+	return fmt.Sprintf("  %s = %s\n", o.backend.attribute, terraform.FormatValueWithOptions(v, 2, o.formatOptions()))
+}
+
+// yamlDocument writes a document as YAML, returning false if
+// it holds Terraform expressions that YAML can't represent
+func yamlDocument(doc cty.Value) (string, bool) {
+	node, ok := yamlNode(doc)
+	if !ok {
+		return "", false
+	}
+	buf := bytes.Buffer{}
+	enc := yaml12.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil || enc.Close() != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// yamlNode converts a value to a YAML node, returning false if
+// it holds Terraform expressions that YAML can't represent
+func yamlNode(v cty.Value) (*yaml12.Node, bool) {
+	ty := v.Type()
+	switch {
+	case ty.IsCapsuleType():
+		return nil, false
+	case v.IsNull():
+		return &yaml12.Node{Kind: yaml12.ScalarNode, Tag: "!!null", Value: "null"}, true
+	case ty == cty.String:
+		return yamlString(v.AsString()), true
+	case ty == cty.Number:
+		tag := "!!float"
+		if v.AsBigFloat().IsInt() {
+			tag = "!!int"
+		}
+		return &yaml12.Node{Kind: yaml12.ScalarNode, Tag: tag, Value: v.AsBigFloat().Text('f', -1)}, true
+	case ty == cty.Bool:
+		return &yaml12.Node{Kind: yaml12.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v.True())}, true
+	case ty.IsObjectType() || ty.IsMapType():
+		node := &yaml12.Node{Kind: yaml12.MappingNode, Tag: "!!map"}
+		for it := v.ElementIterator(); it.Next(); {
+			k, ev := it.Element()
+			en, ok := yamlNode(ev)
+			if !ok {
+				return nil, false
+			}
+			node.Content = append(node.Content, yamlString(k.AsString()), en)
+		}
+		return node, true
+	case ty.IsTupleType() || ty.IsListType() || ty.IsSetType():
+		node := &yaml12.Node{Kind: yaml12.SequenceNode, Tag: "!!seq"}
+		for it := v.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			en, ok := yamlNode(ev)
+			if !ok {
+				return nil, false
+			}
+			node.Content = append(node.Content, en)
+		}
+		return node, true
+	}
+	return nil, false
+}
+
+// yamlString returns a node for a string, quoted when YAML 1.1 or 1.2
+// would read it as something else, since the providers use either
+func yamlString(s string) *yaml12.Node {
+	node := &yaml12.Node{Kind: yaml12.ScalarNode, Tag: "!!str", Value: s}
+	if kind, _ := resolveLegacy(s); kind != kindString || resolveCore(s) != kindString {
+		node.Style = yaml12.DoubleQuotedStyle
+	}
+	return node
+}
+
+// kubectlImportID builds the import id of a document in the form
+// apiVersion//kind//name//namespace that kubectl_manifest uses
+func kubectlImportID(doc cty.Value, namespace string) (importID, bool) {
+	name := getString(doc, "metadata", "name")
+	if name == "" {
+		return importID{}, false
+	}
+
+	parts := []string{getString(doc, "apiVersion"), getString(doc, "kind"), name}
+	id := importID{}
+	for _, p := range parts {
+		literal := quote(p)
+		id.raw += p + "//"
+		id.hcl += literal[1:len(literal)-1] + "//"
+	}
+	if ns, ok := getAttr(doc, "metadata", "namespace"); ok && terraform.IsExpression(ns) {
+		if namespace != "" {
+			literal := quote(namespace)
+			id.raw += namespace
+			id.hcl += literal[1 : len(literal)-1]
+		} else {
+			expr := terraform.ExpressionString(ns)
+			id.raw += "${" + expr + "}"
+			id.hcl += "${" + expr + "}"
+			id.expression = true
+		}
+	} else if ns := getString(doc, "metadata", "namespace"); ns != "" {
+		literal := quote(ns)
+		id.raw += ns
+		id.hcl += literal[1 : len(literal)-1]
+	}
+	id.raw = strings.TrimSuffix(id.raw, "//")
+	id.hcl = `"` + strings.TrimSuffix(id.hcl, "//") + `"`
+	return id, true
+}
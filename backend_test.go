@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const backendYAML = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: web
+data:
+  enabled: "on"
+  mode: "0644"
+  script: |
+    echo ${HOME}
+  count: "3"`
+
+func TestBackendKubectl(t *testing.T) {
+	r := strings.NewReader(backendYAML)
+	output, err := YAMLToTerraformResources(r, "kubectl.east", false, false, false, WithBackend(backendKubectl))
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubectl_manifest" "configmap_web_test" {
+  provider = kubectl.east
+
+  yaml_body = <<-EOT
+  apiVersion: v1
+  data:
+    count: "3"
+    enabled: "on"
+    mode: "0644"
+    script: |
+      echo $${HOME}
+  kind: ConfigMap
+  metadata:
+    name: test
+    namespace: web
+  EOT
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestBackendKubectlExpressions(t *testing.T) {
+	mode, err := parseSetExpr("data.mode=var.mode")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := strings.NewReader(backendYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithBackend(backendKubectl), WithSetExpressions(mode))
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubectl_manifest" "configmap_web_test" {
+  yaml_body = yamlencode({
+    "apiVersion" = "v1"
+    "data" = {
+      "count" = "3"
+      "enabled" = "on"
+      "mode" = var.mode
+      "script" = <<-EOT
+      echo $${HOME}
+      EOT
+    }
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+      "namespace" = "web"
+    }
+  })
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestBackendK8s(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: web`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false, WithBackend(backendK8s))
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "k8s_manifest" "namespace_web" {
+  content = <<-EOT
+  apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: web
+  EOT
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestManifestDecode(t *testing.T) {
+	tests := []struct {
+		target   string
+		function string
+		warning  string
+	}{
+		{target: "terraform@1.8", function: "provider::kubernetes::manifest_decode"},
+		{target: "opentofu@1.7", function: "provider::kubernetes::manifest_decode"},
+		{
+			target:   "terraform@1.5",
+			function: "yamldecode",
+			warning:  "warning: terraform@1.5 does not support provider-defined functions, decoding the manifests with yamldecode() instead\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			target, err := parseTarget(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var warnings bytes.Buffer
+			r := strings.NewReader(backendYAML)
+			output, err := YAMLToTerraformResources(r, "", false, false, false,
+				WithManifestDecode(), WithTarget(target), WithVerify(), WithWarnings(&warnings))
+			if err != nil {
+				t.Fatal("Converting to HCL failed:", err)
+			}
+
+			expected := `
+resource "kubernetes_manifest" "configmap_web_test" {
+  manifest = ` + tt.function + `(<<-EOT
+  apiVersion: v1
+  data:
+    count: "3"
+    enabled: "on"
+    mode: "0644"
+    script: |
+      echo $${HOME}
+  kind: ConfigMap
+  metadata:
+    name: test
+    namespace: web
+  EOT
+  )
+}`
+
+			assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+			assert.Equal(t, tt.warning, warnings.String())
+		})
+	}
+}
+
+func TestManifestDecodeExpressions(t *testing.T) {
+	mode, err := parseSetExpr("data.mode=var.mode")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// YAML can't hold the expression, so the manifest stays an HCL object
+	r := strings.NewReader(backendYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithManifestDecode(), WithTarget(target{toolTerraform, version{1, 8}}), WithSetExpressions(mode), WithVerify())
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+	assert.Contains(t, output, `  manifest = {
+    "apiVersion" = "v1"`)
+	assert.Contains(t, output, `"mode" = var.mode`)
+}
+
+func TestBackendImports(t *testing.T) {
+	o := newOptions("", false, false, false, []Option{WithBackend(backendKubectl), WithImportBlocks()})
+	resources, err := convertManifests(strings.NewReader(importsYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `import {
+  to = kubectl_manifest.configmap_web_test
+  id = "v1//ConfigMap//test//web"
+}
+
+import {
+  to = kubectl_manifest.namespace_web
+  id = "v1//Namespace//web"
+}
+`
+	assert.Equal(t, expected, formatImports(resources, o, "", ""))
+
+	o = newOptions("", false, false, false, []Option{WithBackend(backendK8s), WithImportBlocks()})
+	assert.Equal(t, "", formatImports(resources, o, "", ""))
+}
+
+func TestParseBackend(t *testing.T) {
+	b, err := parseBackend("kubectl")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "kubectl_manifest", b.resourceType)
+	}
+
+	_, err = parseBackend("helm")
+	if assert.Error(t, err) {
+		assert.Equal(t, `unknown backend "helm", must be one of: k8s, kubectl, manifest`, err.Error())
+	}
+}
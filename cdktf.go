@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// language is a programming language that CDK for Terraform code
+// can be written in, with the syntax for its literals
+type language struct {
+	// name selects the language with --format
+	name string
+
+	indent string
+
+	null, true, false string
+
+	// mapOpen and listOpen start a literal, mapClose and listClose end it
+	mapOpen, mapClose   string
+	listOpen, listClose string
+
+	// key formats a map key, including the separator before the value
+	key func(string) string
+
+	// str formats a string literal
+	str func(string) string
+
+	// call formats a call to a Terraform function through the Fn class
+	call func(name string, args []string) string
+
+	// callArg wraps a literal argument of a call, if the function
+	// takes a different type than the literal has
+	callArg func(v cty.Value, literal string) string
+
+	// manifest formats the statement that creates a Manifest resource
+	manifest func(name, literal string) string
+
+	// file wraps the statements in a construct
+	file func(statements []string, functions bool) string
+
+	// format runs the formatter of the language over the file, if any
+	format func([]byte) ([]byte, error)
+}
+
+// typescriptIdentifier matches keys that don't need to be quoted in TypeScript
+var typescriptIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// jsonString formats a string using JSON escapes, which are
+// also valid in TypeScript and Python string literals
+func jsonString(s string) string {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+var (
+	languageTypeScript = language{
+		name:      "cdktf-typescript",
+		indent:    "  ",
+		null:      "null",
+		true:      "true",
+		false:     "false",
+		mapOpen:   "{",
+		mapClose:  "}",
+		listOpen:  "[",
+		listClose: "]",
+		key: func(k string) string {
+			if typescriptIdentifier.MatchString(k) {
+				return k + ": "
+			}
+			return jsonString(k) + ": "
+		},
+		str: jsonString,
+		call: func(name string, args []string) string {
+			return fmt.Sprintf("Fn.%s(%s)", name, strings.Join(args, ", "))
+		},
+		manifest: func(name, literal string) string {
+			return fmt.Sprintf("new Manifest(this, %s, {\n  manifest: %s,\n});\n", jsonString(name), literal)
+		},
+		file: func(statements []string, functions bool) string {
+			imports := `import { Construct } from "constructs";` + "\n"
+			if functions {
+				imports += `import { Fn } from "cdktf";` + "\n"
+			}
+			imports += `import { Manifest } from "@cdktf/provider-kubernetes/lib/manifest";` + "\n"
+			return imports + "\n" +
+				"export class Manifests extends Construct {\n" +
+				"  constructor(scope: Construct, id: string) {\n" +
+				"    super(scope, id);\n" +
+				indentStatements(statements, "    ") +
+				"  }\n" +
+				"}\n"
+		},
+	}
+
+	languagePython = language{
+		name:      "cdktf-python",
+		indent:    "    ",
+		null:      "None",
+		true:      "True",
+		false:     "False",
+		mapOpen:   "{",
+		mapClose:  "}",
+		listOpen:  "[",
+		listClose: "]",
+		key: func(k string) string {
+			return jsonString(k) + ": "
+		},
+		str: jsonString,
+		call: func(name string, args []string) string {
+			return fmt.Sprintf("Fn.%s(%s)", name, strings.Join(args, ", "))
+		},
+		manifest: func(name, literal string) string {
+			return fmt.Sprintf("Manifest(self, %s,\n    manifest=%s,\n)\n", jsonString(name), literal)
+		},
+		file: func(statements []string, functions bool) string {
+			imports := "from constructs import Construct\n"
+			if functions {
+				imports += "from cdktf import Fn\n"
+			}
+			imports += "from cdktf_cdktf_provider_kubernetes.manifest import Manifest\n"
+			return imports + "\n\n" +
+				"class Manifests(Construct):\n" +
+				"    def __init__(self, scope: Construct, id: str):\n" +
+				"        super().__init__(scope, id)\n" +
+				indentStatements(statements, "        ")
+		},
+	}
+
+	languageGo = language{
+		name:      "cdktf-go",
+		indent:    "\t",
+		null:      "nil",
+		true:      "true",
+		false:     "false",
+		mapOpen:   "map[string]interface{}{",
+		mapClose:  "}",
+		listOpen:  "[]interface{}{",
+		listClose: "}",
+		key: func(k string) string {
+			return strconv.Quote(k) + ": "
+		},
+		str: strconv.Quote,
+		call: func(name string, args []string) string {
+			return fmt.Sprintf("cdktf.Fn_%s(%s)", strings.ToUpper(name[:1])+name[1:], strings.Join(args, ", "))
+		},
+		callArg: func(v cty.Value, literal string) string {
+			// the Fn functions take pointers to strings, numbers and bools
+			switch {
+			case terraform.IsFunctionCall(v) || v.IsNull():
+				return literal
+			case v.Type() == cty.String:
+				return "jsii.String(" + literal + ")"
+			case v.Type() == cty.Number:
+				return "jsii.Number(" + literal + ")"
+			case v.Type() == cty.Bool:
+				return "jsii.Bool(" + literal + ")"
+			}
+			return literal
+		},
+		manifest: func(name, literal string) string {
+			return fmt.Sprintf("manifest.NewManifest(c, jsii.String(%s), &manifest.ManifestConfig{\n\tManifest: &%s,\n})\n",
+				strconv.Quote(name), literal)
+		},
+		file: func(statements []string, functions bool) string {
+			imports := "\t\"github.com/aws/constructs-go/constructs/v10\"\n" +
+				"\t\"github.com/aws/jsii-runtime-go\"\n" +
+				"\t\"github.com/cdktf/cdktf-provider-kubernetes-go/kubernetes/v11/manifest\"\n"
+			if functions {
+				imports += "\t\"github.com/hashicorp/terraform-cdk-go/cdktf\"\n"
+			}
+			return "package manifests\n\n" +
+				"import (\n" + imports + ")\n\n" +
+				"func NewManifests(scope constructs.Construct, id *string) constructs.Construct {\n" +
+				"\tc := constructs.NewConstruct(scope, id)\n" +
+				indentStatements(statements, "\t") +
+				"\n\treturn c\n" +
+				"}\n"
+		},
+		format: format.Source,
+	}
+)
+
+// languages are all of the CDK for Terraform languages by --format name
+var languages = map[string]language{
+	languageTypeScript.name: languageTypeScript,
+	languagePython.name:     languagePython,
+	languageGo.name:         languageGo,
+}
+
+// parseFormat returns the language for a --format, or nil for HCL
+func parseFormat(s string) (*language, error) {
+	if s == "hcl" {
+		return nil, nil
+	}
+	if l, ok := languages[s]; ok {
+		return &l, nil
+	}
+	names := []string{"hcl"}
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown format %q, must be one of: %s", s, strings.Join(names, ", "))
+}
+
+// indentStatements puts a blank line before each statement and indents
+// every line that isn't empty
+func indentStatements(statements []string, indent string) string {
+	buf := strings.Builder{}
+	for _, s := range statements {
+		buf.WriteString("\n")
+		for _, line := range strings.SplitAfter(s, "\n") {
+			if strings.TrimSpace(line) != "" {
+				buf.WriteString(indent)
+			}
+			buf.WriteString(line)
+		}
+	}
+	return buf.String()
+}
+
+// formatLiteral is the equivalent of terraform.FormatValue that
+// writes a value as a literal in the language. It reports whether the
+// value calls any Terraform functions through the Fn class. Terraform
+// still evaluates the strings as templates once they are synthesized
+// so they are escaped the same way as in HCL.
+func formatLiteral(v cty.Value, depth int, lang language, mode terraform.Escaping) (string, bool, error) {
+	ty := v.Type()
+	switch {
+	case terraform.IsExpression(v):
+		return "", false, fmt.Errorf("the Terraform expression %s can't be written as %s",
+			terraform.ExpressionString(v), lang.name)
+	case terraform.IsFunctionCall(v):
+		name, args := terraform.FunctionCallArgs(v)
+		formatted := []string{}
+		for _, arg := range args {
+			s, _, err := formatLiteral(arg, depth, lang, mode)
+			if err != nil {
+				return "", false, err
+			}
+			if lang.callArg != nil {
+				s = lang.callArg(arg, s)
+			}
+			formatted = append(formatted, s)
+		}
+		return lang.call(name, formatted), true, nil
+	case v.IsNull():
+		return lang.null, false, nil
+	case ty == cty.String:
+		return lang.str(terraform.EscapeTemplate(v.AsString(), mode)), false, nil
+	case ty == cty.Number:
+		return v.AsBigFloat().Text('f', -1), false, nil
+	case ty == cty.Bool:
+		if v.True() {
+			return lang.true, false, nil
+		}
+		return lang.false, false, nil
+	}
+
+	isMap := ty.IsObjectType() || ty.IsMapType()
+	if !isMap && !ty.IsTupleType() && !ty.IsListType() && !ty.IsSetType() {
+		return "", false, fmt.Errorf("can't write a value of type %s as %s", ty.FriendlyName(), lang.name)
+	}
+
+	open, close := lang.listOpen, lang.listClose
+	if isMap {
+		open, close = lang.mapOpen, lang.mapClose
+	}
+	if v.LengthInt() == 0 {
+		return open + close, false, nil
+	}
+
+	functions := false
+	indent := strings.Repeat(lang.indent, depth+1)
+	buf := strings.Builder{}
+	buf.WriteString(open + "\n")
+	for it := v.ElementIterator(); it.Next(); {
+		k, ev := it.Element()
+		s, calls, err := formatLiteral(ev, depth+1, lang, mode)
+		if err != nil {
+			return "", false, err
+		}
+		functions = functions || calls
+		buf.WriteString(indent)
+		if isMap {
+			buf.WriteString(lang.key(terraform.EscapeTemplate(k.AsString(), mode)))
+		}
+		buf.WriteString(s + ",\n")
+	}
+	buf.WriteString(strings.Repeat(lang.indent, depth) + close)
+	return buf.String(), functions, nil
+}
+
+// formatCDKTF writes the resources as a construct that creates a
+// Manifest for each of them
+func formatCDKTF(resources []resource, o *options) (string, error) {
+	statements := []string{}
+	functions := false
+	for _, r := range resources {
+		literal, calls, err := formatLiteral(r.doc, 1, *o.language, o.escaping)
+		if err != nil {
+			return "", fmt.Errorf("%s: %s", r.name, err)
+		}
+		functions = functions || calls
+		statements = append(statements, o.language.manifest(r.name, literal))
+	}
+	code := o.language.file(statements, functions)
+	if o.language.format != nil {
+		formatted, err := o.language.format([]byte(code))
+		if err != nil {
+			return "", fmt.Errorf("formatting the %s code failed: %s", o.language.name, err)
+		}
+		code = string(formatted)
+	}
+	return code, nil
+}
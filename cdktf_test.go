@@ -0,0 +1,219 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const cdktfYAML = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  labels:
+    app.kubernetes.io/name: test
+data:
+  config.json: '{"debug":true,"level":null}'
+  template: ${HOME}
+  empty: ""
+binaryData: {}`
+
+func TestCDKTFTypeScript(t *testing.T) {
+	r := strings.NewReader(cdktfYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithLanguage(languageTypeScript), WithStructuredData())
+	if err != nil {
+		t.Fatal("Converting to CDKTF failed:", err)
+	}
+
+	expected := `import { Construct } from "constructs";
+import { Fn } from "cdktf";
+import { Manifest } from "@cdktf/provider-kubernetes/lib/manifest";
+
+export class Manifests extends Construct {
+  constructor(scope: Construct, id: string) {
+    super(scope, id);
+
+    new Manifest(this, "configmap_test", {
+      manifest: {
+        apiVersion: "v1",
+        binaryData: {},
+        data: {
+          "config.json": Fn.jsonencode({
+            debug: true,
+            level: null,
+          }),
+          empty: "",
+          template: "$${HOME}",
+        },
+        kind: "ConfigMap",
+        metadata: {
+          labels: {
+            "app.kubernetes.io/name": "test",
+          },
+          name: "test",
+        },
+      },
+    });
+  }
+}
+`
+
+	assert.Equal(t, expected, output)
+}
+
+func TestCDKTFPython(t *testing.T) {
+	r := strings.NewReader(cdktfYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false, WithLanguage(languagePython))
+	if err != nil {
+		t.Fatal("Converting to CDKTF failed:", err)
+	}
+
+	expected := `from constructs import Construct
+from cdktf_cdktf_provider_kubernetes.manifest import Manifest
+
+
+class Manifests(Construct):
+    def __init__(self, scope: Construct, id: str):
+        super().__init__(scope, id)
+
+        Manifest(self, "configmap_test",
+            manifest={
+                "apiVersion": "v1",
+                "binaryData": {},
+                "data": {
+                    "config.json": "{\"debug\":true,\"level\":null}",
+                    "empty": "",
+                    "template": "$${HOME}",
+                },
+                "kind": "ConfigMap",
+                "metadata": {
+                    "labels": {
+                        "app.kubernetes.io/name": "test",
+                    },
+                    "name": "test",
+                },
+            },
+        )
+`
+
+	assert.Equal(t, expected, output)
+}
+
+func TestCDKTFGo(t *testing.T) {
+	r := strings.NewReader(cdktfYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithLanguage(languageGo), WithStructuredData())
+	if err != nil {
+		t.Fatal("Converting to CDKTF failed:", err)
+	}
+
+	expected := `package manifests
+
+import (
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-kubernetes-go/kubernetes/v11/manifest"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+func NewManifests(scope constructs.Construct, id *string) constructs.Construct {
+	c := constructs.NewConstruct(scope, id)
+
+	manifest.NewManifest(c, jsii.String("configmap_test"), &manifest.ManifestConfig{
+		Manifest: &map[string]interface{}{
+			"apiVersion": "v1",
+			"binaryData": map[string]interface{}{},
+			"data": map[string]interface{}{
+				"config.json": cdktf.Fn_Jsonencode(map[string]interface{}{
+					"debug": true,
+					"level": nil,
+				}),
+				"empty":    "",
+				"template": "$${HOME}",
+			},
+			"kind": "ConfigMap",
+			"metadata": map[string]interface{}{
+				"labels": map[string]interface{}{
+					"app.kubernetes.io/name": "test",
+				},
+				"name": "test",
+			},
+		},
+	})
+
+	return c
+}
+`
+
+	assert.Equal(t, expected, output)
+}
+
+func TestCDKTFExpression(t *testing.T) {
+	image, err := parseSetExpr("metadata.name=var.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := strings.NewReader(cdktfYAML)
+	_, err = YAMLToTerraformResources(r, "", false, false, false,
+		WithLanguage(languageTypeScript), WithSetExpressions(image))
+	if assert.Error(t, err) {
+		assert.Equal(t, "configmap_test: the Terraform expression var.name can't be written as cdktf-typescript", err.Error())
+	}
+}
+
+func TestCDKTFGoFunctionArguments(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+data:
+  password: aHVudGVyMg==
+  config.json: eyJkZWJ1ZyI6dHJ1ZX0=`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithLanguage(languageGo), WithDecodedSecrets())
+	if err != nil {
+		t.Fatal("Converting to CDKTF failed:", err)
+	}
+
+	// the Fn functions take *string rather than string
+	expected := `package manifests
+
+import (
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-kubernetes-go/kubernetes/v11/manifest"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+func NewManifests(scope constructs.Construct, id *string) constructs.Construct {
+	c := constructs.NewConstruct(scope, id)
+
+	manifest.NewManifest(c, jsii.String("secret_test"), &manifest.ManifestConfig{
+		Manifest: &map[string]interface{}{
+			"apiVersion": "v1",
+			"data": map[string]interface{}{
+				"config.json": cdktf.Fn_Base64encode(cdktf.Fn_Jsonencode(map[string]interface{}{
+					"debug": true,
+				})),
+				"password": cdktf.Fn_Base64encode(jsii.String("hunter2")),
+			},
+			"kind": "Secret",
+			"metadata": map[string]interface{}{
+				"name": "test",
+			},
+		},
+	})
+
+	return c
+}
+`
+
+	assert.Equal(t, expected, output)
+}
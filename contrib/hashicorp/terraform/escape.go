@@ -0,0 +1,158 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Escaping is how the template sequences ${ and %{ are escaped in strings
+type Escaping int
+
+const (
+	// EscapeLiteral escapes every template sequence so that
+	// strings are read back by Terraform exactly as they are
+	EscapeLiteral Escaping = iota
+
+	// EscapePreserve escapes template sequences that are not already
+	// escaped, for input that was written with Terraform in mind
+	EscapePreserve
+
+	// EscapeInterpolate leaves template sequences alone so
+	// that Terraform interpolates them
+	EscapeInterpolate
+)
+
+var escapingNames = map[string]Escaping{
+	"literal":     EscapeLiteral,
+	"preserve":    EscapePreserve,
+	"interpolate": EscapeInterpolate,
+}
+
+// ParseEscaping returns the escaping mode with the given name
+func ParseEscaping(s string) (Escaping, error) {
+	if e, ok := escapingNames[s]; ok {
+		return e, nil
+	}
+	return 0, fmt.Errorf("unknown escaping mode %q, must be one of: literal, preserve, interpolate", s)
+}
+
+// Heredoc is when multi-line strings are written as heredocs
+type Heredoc int
+
+const (
+	// HeredocAuto writes multi-line strings as heredocs when the
+	// heredoc reads back as exactly the same string
+	HeredocAuto Heredoc = iota
+
+	// HeredocNever writes every string as a quoted string
+	HeredocNever
+
+	// HeredocAlways writes every multi-line string as a heredoc, using
+	// chomp() for strings that don't end with a newline
+	HeredocAlways
+)
+
+var heredocNames = map[string]Heredoc{
+	"auto":   HeredocAuto,
+	"never":  HeredocNever,
+	"always": HeredocAlways,
+}
+
+// ParseHeredoc returns the heredoc policy with the given name
+func ParseHeredoc(s string) (Heredoc, error) {
+	if h, ok := heredocNames[s]; ok {
+		return h, nil
+	}
+	return 0, fmt.Errorf("unknown heredoc policy %q, must be one of: never, auto, always", s)
+}
+
+// EscapeTemplate escapes the template sequences in a string that
+// Terraform will evaluate, such as a value in JSON configuration
+func EscapeTemplate(s string, mode Escaping) string {
+	return escapeTemplate(s, mode)
+}
+
+// escapeTemplate escapes the template sequences ${ and %{ in s
+// by doubling the leading character
+func escapeTemplate(s string, mode Escaping) string {
+	if mode == EscapeInterpolate {
+		return s
+	}
+	if !strings.Contains(s, "${") && !strings.Contains(s, "%{") {
+		return s
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c == '$' || c == '%') && i+1 < len(s) && s[i+1] == '{' {
+			escaped := i > 0 && s[i-1] == c
+			if !(mode == EscapePreserve && escaped) {
+				buf.WriteByte(c)
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
+
+// quoteString writes s as an HCL quoted string, escaping characters that
+// can't appear in one. Terraform strings can only hold valid UTF-8, so
+// invalid bytes are written as U+FFFD. Callers that need the exact bytes
+// have to reject such strings before formatting them.
+func quoteString(s string, mode Escaping) string {
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	s = escapeTemplate(s, mode)
+
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			switch {
+			case unicode.IsPrint(r) || r == ' ':
+				buf.WriteRune(r)
+			case r > 0xffff:
+				fmt.Fprintf(&buf, `\U%08x`, r)
+			default:
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// quoteKey writes s as an object key
+func quoteKey(s string, mode Escaping) string {
+	return quoteString(s, mode)
+}
+
+// heredocSafe returns true if s can be written as a heredoc, which
+// has no escape sequences so it can only hold printable characters
+func heredocSafe(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r != '\n' && r != '\t' && !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+		if r == '\r' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestFormatValueEscaping(t *testing.T) {
+	tests := []struct {
+		Val      cty.Value
+		Escaping Escaping
+		Want     string
+	}{
+		{
+			cty.StringVal("${HOME} and %{ if x }"),
+			EscapeLiteral,
+			`"$${HOME} and %%{ if x }"`,
+		},
+		{
+			cty.StringVal("$${HOME} and %%{ if x }"),
+			EscapeLiteral,
+			`"$$${HOME} and %%%{ if x }"`,
+		},
+		{
+			cty.StringVal("$${HOME} and ${USER}"),
+			EscapePreserve,
+			`"$${HOME} and $${USER}"`,
+		},
+		{
+			cty.StringVal("${var.name}-%{ if var.x }x%{ endif }"),
+			EscapeInterpolate,
+			`"${var.name}-%{ if var.x }x%{ endif }"`,
+		},
+		{
+			cty.StringVal("$HOME and 100%"),
+			EscapeLiteral,
+			`"$HOME and 100%"`,
+		},
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"${key}": cty.StringVal("echo ${A}\necho %{B}\n"),
+				"expr":   ExpressionVal(`"${var.a}"`),
+			}),
+			EscapeLiteral,
+			`{
+  "$${key}" = <<-EOT
+  echo $${A}
+  echo %%{B}
+  EOT
+  "expr" = "${var.a}"
+}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Want, func(t *testing.T) {
+			got := FormatValueWithOptions(test.Val, 0, FormatOptions{Escaping: test.Escaping})
+			if got != test.Want {
+				t.Errorf("wrong result\nvalue: %#v\ngot:   %s\nwant:  %s", test.Val, got, test.Want)
+			}
+		})
+	}
+}
+
+func TestParseEscaping(t *testing.T) {
+	for name, want := range escapingNames {
+		got, err := ParseEscaping(name)
+		if err != nil || got != want {
+			t.Errorf("ParseEscaping(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseEscaping("none"); err == nil {
+		t.Error("expected an error for an unknown escaping mode")
+	}
+}
+
+// parseHCLString parses src as the value of an attribute and returns the
+// string it evaluates to
+func parseHCLString(t *testing.T, src string) string {
+	t.Helper()
+	f, diags := hclsyntax.ParseConfig([]byte("x = "+src+"\n"), "test.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("invalid HCL %s: %s", src, diags.Error())
+	}
+	attrs, _ := f.Body.JustAttributes()
+	v, diags := attrs["x"].Expr.Value(nil)
+	if diags.HasErrors() {
+		t.Fatalf("could not evaluate %s: %s", src, diags.Error())
+	}
+	return v.AsString()
+}
+
+func TestQuoteString(t *testing.T) {
+	tests := []struct {
+		Val  string
+		Want string
+	}{
+		{"hello", `"hello"`},
+		{`say "hi" \ bye`, `"say \"hi\" \\ bye"`},
+		{"tab\tnewline\ncr\r", `"tab\tnewline\ncr\r"`},
+		{"nul\x00bell\aform\fvtab\v", `"nul\u0000bell\u0007form\u000cvtab\u000b"`},
+		{"del\x7f", `"del\u007f"`},
+		{"line\u2028separator", `"line\u2028separator"`},
+		{"emoji 🎉 ünïcode", `"emoji 🎉 ünïcode"`},
+		{"^[\\x00-\\x1f]+$", `"^[\\x00-\\x1f]+$"`},
+		{"${HOME}", `"$${HOME}"`},
+		// Terraform strings can't hold invalid UTF-8
+		{"bad \xff\xfe utf8", "\"bad \ufffd utf8\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Want, func(t *testing.T) {
+			got := quoteString(test.Val, EscapeLiteral)
+			if got != test.Want {
+				t.Errorf("wrong result\ngot:   %s\nwant:  %s", got, test.Want)
+			}
+			if parsed := parseHCLString(t, got); parsed != strings.ToValidUTF8(test.Val, "\ufffd") {
+				t.Errorf("did not round-trip\ngot:   %q\nwant:  %q", parsed, test.Val)
+			}
+		})
+	}
+}
+
+func TestFormatValueControlCharacters(t *testing.T) {
+	// heredocs have no escape sequences so strings with
+	// control characters must always be quoted
+	got := FormatValue(cty.StringVal("a\x1b[0m\nb"), 0, false)
+	want := `"a\u001b[0m\nb"`
+	if got != want {
+		t.Errorf("wrong result\ngot:   %s\nwant:  %s", got, want)
+	}
+
+	got = FormatValue(cty.ObjectVal(map[string]cty.Value{"a\x00b": cty.True}), 0, true)
+	want = "{\n  \"a\\u0000b\" = true\n}"
+	if got != want {
+		t.Errorf("wrong result\ngot:   %s\nwant:  %s", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package terraform
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// expressionType is a capsule type for values that hold a Terraform
+// expression, such as a variable reference, rather than a literal value
+var expressionType = cty.Capsule("expression", reflect.TypeOf(""))
+
+// ExpressionVal returns a value that FormatValue writes out verbatim
+// instead of formatting it as a literal
+func ExpressionVal(expr string) cty.Value {
+	return cty.CapsuleVal(expressionType, &expr)
+}
+
+// IsExpression returns true if the value was created with ExpressionVal
+func IsExpression(v cty.Value) bool {
+	return v.Type().Equals(expressionType)
+}
+
+// ExpressionString returns the source of a value created with ExpressionVal
+func ExpressionString(v cty.Value) string {
+	return formatExpression(v)
+}
+
+// formatExpression returns the source of an expression value
+func formatExpression(v cty.Value) string {
+	return *v.EncapsulatedValue().(*string)
+}
+
+// functionCall is a call to a Terraform function
+type functionCall struct {
+	name string
+	args []cty.Value
+}
+
+// functionCallType is a capsule type for values that are the result of
+// calling a Terraform function, such as jsonencode, on other values
+var functionCallType = cty.Capsule("function call", reflect.TypeOf(functionCall{}))
+
+// FunctionCallVal returns a value that FormatValue writes out as a call to
+// the named function, with each of the arguments formatted as usual
+func FunctionCallVal(name string, args ...cty.Value) cty.Value {
+	return cty.CapsuleVal(functionCallType, &functionCall{name: name, args: args})
+}
+
+// IsFunctionCall returns true if the value was created with FunctionCallVal
+func IsFunctionCall(v cty.Value) bool {
+	return v.Type().Equals(functionCallType)
+}
+
+// FunctionCallArgs returns the name and arguments of a value
+// created with FunctionCallVal
+func FunctionCallArgs(v cty.Value) (string, []cty.Value) {
+	call := v.EncapsulatedValue().(*functionCall)
+	return call.name, call.args
+}
+
+// formatFunctionCall writes out a function call with its arguments
+func formatFunctionCall(v cty.Value, indent int, opts FormatOptions) string {
+	call := v.EncapsulatedValue().(*functionCall)
+
+	var buf strings.Builder
+	buf.WriteString(call.name)
+	buf.WriteByte('(')
+	formatted := ""
+	for i, arg := range call.args {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		formatted = FormatValueWithOptions(arg, indent, opts)
+		buf.WriteString(formatted)
+	}
+	if endsWithHeredoc(formatted) {
+		// the closing parenthesis can't go on the same line as the heredoc delimiter
+		buf.WriteByte('\n')
+		buf.WriteString(strings.Repeat(" ", indent))
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+// isMultilineString returns true if the value will be written as a heredoc
+func isMultilineString(v cty.Value) bool {
+	return v.Type() == cty.String && v.IsKnown() && !v.IsNull() && !v.IsMarked() &&
+		strings.Contains(v.AsString(), "\n") && heredocSafe(v.AsString())
+}
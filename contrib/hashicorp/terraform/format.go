@@ -9,17 +9,36 @@ package terraform
 import (
 	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/zclconf/go-cty/cty"
 )
 
+// FormatOptions controls how FormatValueWithOptions writes values
+type FormatOptions struct {
+	// StripKeyQuotes removes the quotes from object keys that don't need them
+	StripKeyQuotes bool
+
+	// Escaping is how template sequences in strings and keys are escaped
+	Escaping Escaping
+
+	// Heredoc is when multi-line strings are written as heredocs
+	Heredoc Heredoc
+}
+
 // FormatValue formats a value in a way that resembles Terraform language syntax
 // and uses the type conversion functions where necessary to indicate exactly
 // what type it is given, so that equality test failures can be quickly
 // understood.
 func FormatValue(v cty.Value, indent int, stripKeyQuotes bool) string {
+	return FormatValueWithOptions(v, indent, FormatOptions{StripKeyQuotes: stripKeyQuotes})
+}
+
+// FormatValueWithOptions is like FormatValue but allows more control
+// over how the value is written
+func FormatValueWithOptions(v cty.Value, indent int, opts FormatOptions) string {
 	if !v.IsKnown() {
 		return "(known after apply)"
 	}
@@ -50,13 +69,17 @@ func FormatValue(v cty.Value, indent int, stripKeyQuotes bool) string {
 
 	ty := v.Type()
 	switch {
+	case IsExpression(v):
+		return formatExpression(v)
+	case IsFunctionCall(v):
+		return formatFunctionCall(v, indent, opts)
 	case ty.IsPrimitiveType():
 		switch ty {
 		case cty.String:
-			if formatted, isMultiline := formatMultilineString(v, indent); isMultiline {
+			if formatted, isMultiline := formatMultilineString(v, indent, opts); isMultiline {
 				return formatted
 			}
-			return strconv.Quote(v.AsString())
+			return quoteString(v.AsString(), opts.Escaping)
 		case cty.Number:
 			bf := v.AsBigFloat()
 			return bf.Text('f', -1)
@@ -68,15 +91,15 @@ func FormatValue(v cty.Value, indent int, stripKeyQuotes bool) string {
 			}
 		}
 	case ty.IsObjectType():
-		return formatMappingValue(v, indent, stripKeyQuotes)
+		return formatMappingValue(v, indent, opts)
 	case ty.IsTupleType():
-		return formatSequenceValue(v, indent, stripKeyQuotes)
+		return formatSequenceValue(v, indent, opts)
 	case ty.IsListType():
-		return fmt.Sprintf("tolist(%s)", formatSequenceValue(v, indent, stripKeyQuotes))
+		return fmt.Sprintf("tolist(%s)", formatSequenceValue(v, indent, opts))
 	case ty.IsSetType():
-		return fmt.Sprintf("toset(%s)", formatSequenceValue(v, indent, stripKeyQuotes))
+		return fmt.Sprintf("toset(%s)", formatSequenceValue(v, indent, opts))
 	case ty.IsMapType():
-		return fmt.Sprintf("tomap(%s)", formatMappingValue(v, indent, stripKeyQuotes))
+		return fmt.Sprintf("tomap(%s)", formatMappingValue(v, indent, opts))
 	}
 
 	// Should never get here because there are no other types
@@ -86,16 +109,57 @@ func FormatValue(v cty.Value, indent int, stripKeyQuotes bool) string {
 // defaultDelimiter is "End Of Text" by convention
 const defaultDelimiter = "EOT"
 
-func formatMultilineString(v cty.Value, indent int) (string, bool) {
-	str := v.AsString()
-	lines := strings.Split(str, "\n")
-	if len(lines) < 2 {
+// heredocEnd matches the closing delimiter of a heredoc at the end of a value
+var heredocEnd = regexp.MustCompile(`\n[ \t]*` + defaultDelimiter + `_*$`)
+
+// endsWithHeredoc returns true if the formatted value ends with a heredoc,
+// which means nothing else can follow it on the same line
+func endsWithHeredoc(formatted string) bool {
+	return heredocEnd.MatchString(formatted)
+}
+
+// commonIndent returns the number of leading whitespace characters
+// shared by all of the lines that aren't blank
+func commonIndent(lines []string) int {
+	min := -1
+	for _, line := range lines {
+		trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+		if trimmed == "" {
+			continue
+		}
+		n := utf8.RuneCountInString(line[:len(line)-len(trimmed)])
+		if min == -1 || n < min {
+			min = n
+		}
+	}
+	if min == -1 {
+		return 0
+	}
+	return min
+}
+
+// formatMultilineString writes a string as a heredoc, if the heredoc
+// policy allows it and the heredoc would read back as exactly the same string
+func formatMultilineString(v cty.Value, indent int, opts FormatOptions) (string, bool) {
+	if opts.Heredoc == HeredocNever || !isMultilineString(v) {
 		return "", false
 	}
+	str := escapeTemplate(v.AsString(), opts.Escaping)
+
+	// A heredoc always ends with a newline, so strings that don't
+	// have one need to be wrapped in chomp() to remove it again
+	chomp := !strings.HasSuffix(str, "\n")
+	if chomp && opts.Heredoc != HeredocAlways {
+		return "", false
+	}
+	lines := strings.Split(strings.TrimSuffix(str, "\n"), "\n")
 
 	// If the value is indented, we use the indented form of heredoc for readability.
+	// The indented form removes the leading whitespace that all of the lines have
+	// in common, so it can't be used if the lines are already indented.
 	operator := "<<"
-	if indent > 0 {
+	flush := indent > 0 && commonIndent(lines) == 0
+	if flush {
 		operator = "<<-"
 	}
 
@@ -121,21 +185,32 @@ OUTER:
 	// Write the heredoc, with indentation as appropriate.
 	var buf strings.Builder
 
+	if chomp {
+		buf.WriteString("chomp(")
+	}
 	buf.WriteString(operator)
 	buf.WriteString(delimiter)
 	for _, line := range lines {
 		buf.WriteByte('\n')
-		buf.WriteString(strings.Repeat(" ", indent))
+		// blank lines are left alone by the indented form so they can't be indented
+		if flush && strings.TrimSpace(line) != "" {
+			buf.WriteString(strings.Repeat(" ", indent))
+		}
 		buf.WriteString(line)
 	}
 	buf.WriteByte('\n')
 	buf.WriteString(strings.Repeat(" ", indent))
 	buf.WriteString(delimiter)
+	if chomp {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.Repeat(" ", indent))
+		buf.WriteByte(')')
+	}
 
 	return buf.String(), true
 }
 
-func formatMappingValue(v cty.Value, indent int, stripKeyQuotes bool) string {
+func formatMappingValue(v cty.Value, indent int, opts FormatOptions) string {
 	var buf strings.Builder
 	count := 0
 	buf.WriteByte('{')
@@ -145,8 +220,8 @@ func formatMappingValue(v cty.Value, indent int, stripKeyQuotes bool) string {
 		k, v := it.Element()
 		buf.WriteByte('\n')
 		buf.WriteString(strings.Repeat(" ", indent))
-		key := FormatValue(k, indent, stripKeyQuotes)
-		if stripKeyQuotes {
+		key := quoteKey(k.AsString(), opts.Escaping)
+		if opts.StripKeyQuotes {
 			// they can be unquoted if it starts with a letter
 			// and only contains alphanumeric characeters, dashes, and underlines
 			m := regexp.MustCompile(`^"[A-Za-z][0-9A-Za-z-_]+"$`)
@@ -156,7 +231,7 @@ func formatMappingValue(v cty.Value, indent int, stripKeyQuotes bool) string {
 		}
 		buf.WriteString(key)
 		buf.WriteString(" = ")
-		buf.WriteString(FormatValue(v, indent, stripKeyQuotes))
+		buf.WriteString(FormatValueWithOptions(v, indent, opts))
 	}
 	indent -= 2
 	if count > 0 {
@@ -167,7 +242,7 @@ func formatMappingValue(v cty.Value, indent int, stripKeyQuotes bool) string {
 	return buf.String()
 }
 
-func formatSequenceValue(v cty.Value, indent int, stripKeyQuotes bool) string {
+func formatSequenceValue(v cty.Value, indent int, opts FormatOptions) string {
 	var buf strings.Builder
 	count := 0
 	buf.WriteByte('[')
@@ -177,9 +252,9 @@ func formatSequenceValue(v cty.Value, indent int, stripKeyQuotes bool) string {
 		_, v := it.Element()
 		buf.WriteByte('\n')
 		buf.WriteString(strings.Repeat(" ", indent))
-		formattedValue := FormatValue(v, indent, stripKeyQuotes)
+		formattedValue := FormatValueWithOptions(v, indent, opts)
 		buf.WriteString(formattedValue)
-		if strings.HasSuffix(formattedValue, defaultDelimiter) {
+		if endsWithHeredoc(formattedValue) {
 			// write an additional newline if the value was a multiline string
 			buf.WriteByte('\n')
 			buf.WriteString(strings.Repeat(" ", indent))
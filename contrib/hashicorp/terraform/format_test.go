@@ -60,14 +60,14 @@ func TestFormatValue(t *testing.T) {
 			`"hello"`,
 		},
 		{
-			cty.StringVal("hello\nworld"),
+			cty.StringVal("hello\nworld\n"),
 			`<<EOT
 hello
 world
 EOT`,
 		},
 		{
-			cty.StringVal("EOR\nEOS\nEOT\nEOU"),
+			cty.StringVal("EOR\nEOS\nEOT\nEOU\n"),
 			`<<EOT_
 EOR
 EOS
@@ -76,7 +76,7 @@ EOU
 EOT_`,
 		},
 		{
-			cty.ObjectVal(map[string]cty.Value{"foo": cty.StringVal("boop\nbeep")}),
+			cty.ObjectVal(map[string]cty.Value{"foo": cty.StringVal("boop\nbeep\n")}),
 			`{
   "foo" = <<-EOT
   boop
@@ -166,7 +166,7 @@ EOT_`,
 		},
 		{
 			cty.TupleVal([]cty.Value{
-				cty.StringVal("boop\nbeep"),
+				cty.StringVal("boop\nbeep\n"),
 				cty.StringVal("b"),
 			}),
 			`[
@@ -181,7 +181,7 @@ EOT_`,
 		{
 			cty.TupleVal([]cty.Value{
 				cty.StringVal("b"),
-				cty.StringVal("boop\nbeep"),
+				cty.StringVal("boop\nbeep\n"),
 			}),
 			`[
   "b",
@@ -204,6 +204,39 @@ EOT_`,
 			cty.StringVal("sensitive value").Mark("sensitive"),
 			"(sensitive)",
 		},
+		{
+			ExpressionVal("var.namespace"),
+			`var.namespace`,
+		},
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"image": ExpressionVal(`"${var.registry}/app:${var.tag}"`),
+			}),
+			`{
+  "image" = "${var.registry}/app:${var.tag}"
+}`,
+		},
+		{
+			FunctionCallVal("base64encode", cty.StringVal("hello")),
+			`base64encode("hello")`,
+		},
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"config.json": FunctionCallVal("base64encode", FunctionCallVal("jsonencode",
+					cty.ObjectVal(map[string]cty.Value{"a": cty.NumberIntVal(1)}))),
+				"script.sh": FunctionCallVal("base64encode", cty.StringVal("echo hello\necho world\n")),
+			}),
+			`{
+  "config.json" = base64encode(jsonencode({
+    "a" = 1
+  }))
+  "script.sh" = base64encode(<<-EOT
+  echo hello
+  echo world
+  EOT
+  )
+}`,
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,141 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// evalHCL parses src as the value of an attribute and evaluates it
+func evalHCL(t *testing.T, src string) cty.Value {
+	t.Helper()
+	f, diags := hclsyntax.ParseConfig([]byte("x = "+src+"\n"), "test.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("invalid HCL: %s\n%s", diags.Error(), src)
+	}
+	attrs, _ := f.Body.JustAttributes()
+	ctx := &hcl.EvalContext{
+		Functions: map[string]function.Function{
+			"chomp": stdlib.ChompFunc,
+		},
+	}
+	v, diags := attrs["x"].Expr.Value(ctx)
+	if diags.HasErrors() {
+		t.Fatalf("could not evaluate: %s\n%s", diags.Error(), src)
+	}
+	return v
+}
+
+var heredocStrings = []string{
+	"one line\n",
+	"hello\nworld\n",
+	"no trailing newline\nhere",
+	"two trailing newlines\n\n",
+	"\nleading newline\n",
+	"  indented\n  lines\n",
+	"  mixed\nindentation\n",
+	"\ttabs\n\tonly\n",
+	"blank\n\nline\n",
+	"whitespace\n   \nline\n",
+	"EOT\nEOT_\n",
+	"  EOT\n",
+	"${shell} %{directive}\n",
+}
+
+func TestHeredocRoundTrip(t *testing.T) {
+	policies := []Heredoc{HeredocAuto, HeredocNever, HeredocAlways}
+	for _, policy := range policies {
+		for _, s := range heredocStrings {
+			v := cty.StringVal(s)
+			values := map[string]cty.Value{
+				"top":    v,
+				"object": cty.ObjectVal(map[string]cty.Value{"a": cty.ObjectVal(map[string]cty.Value{"b": v})}),
+				"tuple":  cty.TupleVal([]cty.Value{v, v}),
+				"call":   FunctionCallVal("chomp", v),
+			}
+			for name, value := range values {
+				formatted := FormatValueWithOptions(value, 0, FormatOptions{Heredoc: policy})
+				got := evalHCL(t, formatted)
+				want := value
+				if name == "call" {
+					want = cty.StringVal(strings.TrimRight(s, "\n"))
+				}
+				if !got.RawEquals(want) {
+					t.Errorf("%s with policy %d did not round-trip\nformatted:\n%s\ngot:  %#v\nwant: %#v",
+						name, policy, formatted, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestHeredocPolicy(t *testing.T) {
+	tests := []struct {
+		Val    string
+		Policy Heredoc
+		Want   string
+	}{
+		{
+			"a\nb\n",
+			HeredocAuto,
+			"{\n  \"k\" = <<-EOT\n  a\n  b\n  EOT\n}",
+		},
+		{
+			"a\nb",
+			HeredocAuto,
+			"{\n  \"k\" = \"a\\nb\"\n}",
+		},
+		{
+			"a\nb",
+			HeredocAlways,
+			"{\n  \"k\" = chomp(<<-EOT\n  a\n  b\n  EOT\n  )\n}",
+		},
+		{
+			"a\nb\n",
+			HeredocNever,
+			"{\n  \"k\" = \"a\\nb\\n\"\n}",
+		},
+		{
+			// every line is indented so the indented form can't be used
+			"  a\n  b\n",
+			HeredocAuto,
+			"{\n  \"k\" = <<EOT\n  a\n  b\n  EOT\n}",
+		},
+		{
+			// blank lines are not indented
+			"a\n\nb\n",
+			HeredocAuto,
+			"{\n  \"k\" = <<-EOT\n  a\n\n  b\n  EOT\n}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Want, func(t *testing.T) {
+			v := cty.ObjectVal(map[string]cty.Value{"k": cty.StringVal(test.Val)})
+			got := FormatValueWithOptions(v, 0, FormatOptions{Heredoc: test.Policy})
+			if got != test.Want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestHeredocInSequenceWithLongDelimiter(t *testing.T) {
+	v := cty.TupleVal([]cty.Value{cty.StringVal("EOT\n"), cty.StringVal("b")})
+	got := FormatValue(v, 0, false)
+	want := `[
+  <<-EOT_
+  EOT
+  EOT_
+  ,
+  "b",
+]`
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// crdStageDir holds the CustomResourceDefinitions, applied first
+	crdStageDir = "1-crds"
+
+	// resourceStageDir holds everything else, applied once the CRDs exist
+	resourceStageDir = "2-resources"
+)
+
+// builtinGroups are the API groups served by Kubernetes itself
+var builtinGroups = []string{
+	"admissionregistration.k8s.io",
+	"apiextensions.k8s.io",
+	"apiregistration.k8s.io",
+	"apps",
+	"authentication.k8s.io",
+	"authorization.k8s.io",
+	"autoscaling",
+	"batch",
+	"certificates.k8s.io",
+	"coordination.k8s.io",
+	"core",
+	"discovery.k8s.io",
+	"events.k8s.io",
+	"extensions",
+	"flowcontrol.apiserver.k8s.io",
+	"internal.apiserver.k8s.io",
+	"networking.k8s.io",
+	"node.k8s.io",
+	"policy",
+	"rbac.authorization.k8s.io",
+	"resource.k8s.io",
+	"scheduling.k8s.io",
+	"storage.k8s.io",
+	"storagemigration.k8s.io",
+}
+
+// webhookKinds are moved into the CRD stage with --crd-stage-webhooks
+var webhookKinds = []string{
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+// crdKinds returns the group/kind of every custom resource
+// defined by a CustomResourceDefinition in the input
+func crdKinds(resources []resource) map[string]bool {
+	kinds := map[string]bool{}
+	for _, r := range resources {
+		if getString(r.doc, "kind") != "CustomResourceDefinition" {
+			continue
+		}
+		group := getString(r.doc, "spec", "group")
+		if kind := getString(r.doc, "spec", "names", "kind"); kind != "" {
+			kinds[group+"/"+kind] = true
+		}
+	}
+	return kinds
+}
+
+// splitStages splits the resources into the CRDs, which have to exist
+// before custom resources can be planned, and everything else. It warns
+// about custom resources whose CRD is not in the input.
+func splitStages(resources []resource, o *options) ([]resource, []resource) {
+	defined := crdKinds(resources)
+	crds := []resource{}
+	rest := []resource{}
+	missing := []string{}
+	for _, r := range resources {
+		kind := getString(r.doc, "kind")
+		if kind == "CustomResourceDefinition" || (o.crdStageWebhooks && contains(webhookKinds, kind)) {
+			crds = append(crds, r)
+			continue
+		}
+		rest = append(rest, r)
+
+		group := apiGroup(r.doc)
+		if !contains(builtinGroups, group) && !defined[group+"/"+kind] {
+			missing = append(missing, fmt.Sprintf("%s (%s %s)", r.name, getString(r.doc, "apiVersion"), kind))
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		o.warnf("the CustomResourceDefinitions of these custom resources are not in the input, so they have to exist before %s can be planned: %s",
+			resourceStageDir, strings.Join(missing, ", "))
+	}
+	return crds, rest
+}
+
+// writeStages writes the CRDs and the other resources as separate root
+// configurations in dir, to be applied one after the other
+func writeStages(dir string, resources []resource, o *options) error {
+	crds, rest := splitStages(resources, o)
+	if len(crds) == 0 {
+		o.warnf("there are no CustomResourceDefinitions in the input, so only %s is written", resourceStageDir)
+	}
+
+	stages := []struct {
+		dir       string
+		resources []resource
+	}{
+		{crdStageDir, crds},
+		{resourceStageDir, rest},
+	}
+	for _, s := range stages {
+		if len(s.resources) == 0 {
+			continue
+		}
+		hcl, err := formatConfig(s.resources, o)
+		if err != nil {
+			return err
+		}
+		stageDir := filepath.Join(dir, s.dir)
+		if err := os.MkdirAll(stageDir, 0755); err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(stageDir, "main.tf"), []byte(hcl)); err != nil {
+			return err
+		}
+		if err := writeFiles(stageDir, s.resources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
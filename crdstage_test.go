@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const crdStageYAML = `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: issuers.cert-manager.io
+spec:
+  group: cert-manager.io
+  scope: Namespaced
+  names:
+    kind: Issuer
+    plural: issuers
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: webhook
+  namespace: cert-manager
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: cert-manager-webhook
+---
+apiVersion: cert-manager.io/v1
+kind: Issuer
+metadata:
+  name: selfsigned
+  namespace: cert-manager
+spec:
+  selfSigned: {}
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: web
+  namespace: cert-manager
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: webhook
+  namespace: cert-manager
+`
+
+func TestSplitStages(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		crds      []string
+		resources []string
+	}{
+		{
+			name: "crds",
+			crds: []string{"customresourcedefinition_issuers_cert_manager_io"},
+			resources: []string{
+				"deployment_cert_manager_webhook",
+				"validatingwebhookconfiguration_cert_manager_webhook",
+				"issuer_cert_manager_selfsigned",
+				"certificate_cert_manager_web",
+				"servicemonitor_cert_manager_webhook",
+			},
+		},
+		{
+			name: "webhooks",
+			opts: []Option{WithCRDStageWebhooks()},
+			crds: []string{
+				"customresourcedefinition_issuers_cert_manager_io",
+				"validatingwebhookconfiguration_cert_manager_webhook",
+			},
+			resources: []string{
+				"deployment_cert_manager_webhook",
+				"issuer_cert_manager_selfsigned",
+				"certificate_cert_manager_web",
+				"servicemonitor_cert_manager_webhook",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings bytes.Buffer
+			o := newOptions("", false, false, false, append(tt.opts, WithWarnings(&warnings)))
+			resources, err := convertManifests(strings.NewReader(crdStageYAML), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			crds, rest := splitStages(resources, o)
+			names := func(resources []resource) []string {
+				n := []string{}
+				for _, r := range resources {
+					n = append(n, r.name)
+				}
+				return n
+			}
+			assert.Equal(t, tt.crds, names(crds))
+			assert.Equal(t, tt.resources, names(rest))
+
+			// the Issuer has its CRD in the input, the others don't
+			assert.Equal(t, "warning: the CustomResourceDefinitions of these custom resources are not in the input, "+
+				"so they have to exist before 2-resources can be planned: "+
+				"certificate_cert_manager_web (cert-manager.io/v1 Certificate), "+
+				"servicemonitor_cert_manager_webhook (monitoring.coreos.com/v1 ServiceMonitor)\n", warnings.String())
+		})
+	}
+}
+
+func TestSplitStagesWithoutAPIVersion(t *testing.T) {
+	yaml := `---
+kind: ConfigMap
+metadata:
+  name: settings
+`
+	var warnings bytes.Buffer
+	o := newOptions("", false, false, false, []Option{WithWarnings(&warnings)})
+	resources, err := convertManifests(strings.NewReader(yaml), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crds, rest := splitStages(resources, o)
+	assert.Empty(t, crds)
+	assert.Len(t, rest, 1)
+	assert.Empty(t, warnings.String())
+}
+
+func TestWriteStages(t *testing.T) {
+	var warnings bytes.Buffer
+	o := newOptions("", false, false, false, []Option{WithWarnings(&warnings)})
+	resources, err := convertManifests(strings.NewReader(crdStageYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := writeStages(dir, resources, o); err != nil {
+		t.Fatal(err)
+	}
+
+	crds, err := os.ReadFile(filepath.Join(dir, "1-crds", "main.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(crds), `resource "kubernetes_manifest" "customresourcedefinition_issuers_cert_manager_io"`)
+	assert.NotContains(t, string(crds), `"issuer_cert_manager_selfsigned"`)
+
+	rest, err := os.ReadFile(filepath.Join(dir, "2-resources", "main.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(rest), `resource "kubernetes_manifest" "issuer_cert_manager_selfsigned"`)
+	assert.Contains(t, string(rest), `resource "kubernetes_manifest" "deployment_cert_manager_webhook"`)
+	assert.NotContains(t, string(rest), "CustomResourceDefinition")
+}
+
+func TestWriteStagesWithoutCRDs(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`
+	var warnings bytes.Buffer
+	o := newOptions("", false, false, false, []Option{WithWarnings(&warnings)})
+	resources, err := convertManifests(strings.NewReader(yaml), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := writeStages(dir, resources, o); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "1-crds"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "2-resources", "main.tf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "warning: there are no CustomResourceDefinitions in the input, so only 2-resources is written\n", warnings.String())
+}
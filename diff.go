@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// existingResource is a resource read from an existing configuration
+type existingResource struct {
+	name string
+	doc  cty.Value
+}
+
+// readConfiguration reads the resources of the backend's resource
+// type from the .tf files in a directory
+func readConfiguration(dir string, o *options) ([]existingResource, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("there are no .tf files in %s", dir)
+	}
+
+	existing := []existingResource{}
+	seen := map[string]string{}
+	for _, filename := range files {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		resources, err := parseConfiguration(src, filename, o)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resources {
+			if other, ok := seen[r.name]; ok {
+				return nil, fmt.Errorf("%s.%s is in both %s and %s", o.backend.resourceType, r.name, other, filename)
+			}
+			seen[r.name] = filename
+		}
+		existing = append(existing, resources...)
+	}
+	return existing, nil
+}
+
+// parseConfiguration reads the resources of the backend's resource type
+// from the source of a .tf file. Values that can't be evaluated without
+// the rest of the configuration are read as expressions and function calls.
+func parseConfiguration(src []byte, filename string, o *options) ([]existingResource, error) {
+	f, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s", diags.Error())
+	}
+
+	resources := []existingResource{}
+	for _, b := range f.Body.(*hclsyntax.Body).Blocks {
+		if b.Type != "resource" || len(b.Labels) != 2 || b.Labels[0] != o.backend.resourceType {
+			continue
+		}
+		address := fmt.Sprintf("%s.%s", b.Labels[0], b.Labels[1])
+		attr, ok := b.Body.Attributes[o.backend.attribute]
+		if !ok {
+			o.warnf("%s has no %s, skipping it", address, o.backend.attribute)
+			continue
+		}
+
+		doc := configValue(attr.Expr, src)
+		if o.backend.yaml || terraform.IsFunctionCall(doc) {
+			var err error
+			doc, err = yamlConfigValue(doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %s", address, o.backend.attribute, err)
+			}
+		}
+		resources = append(resources, existingResource{name: b.Labels[1], doc: doc})
+	}
+	return resources, nil
+}
+
+// configValue reads an expression as a value the same way tfk8s
+// would have written it
+func configValue(expr hclsyntax.Expression, src []byte) cty.Value {
+	switch e := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		attrs := map[string]cty.Value{}
+		for _, item := range e.Items {
+			key := strings.TrimSpace(string(item.KeyExpr.Range().SliceBytes(src)))
+			if k, diags := item.KeyExpr.Value(verifyContext); !diags.HasErrors() && k.Type() == cty.String && !k.IsNull() {
+				key = k.AsString()
+			}
+			attrs[key] = configValue(item.ValueExpr, src)
+		}
+		return cty.ObjectVal(attrs)
+	case *hclsyntax.TupleConsExpr:
+		elems := []cty.Value{}
+		for _, el := range e.Exprs {
+			elems = append(elems, configValue(el, src))
+		}
+		return cty.TupleVal(elems)
+	case *hclsyntax.FunctionCallExpr:
+		if _, ok := verifyContext.Functions[e.Name]; !ok {
+			args := []cty.Value{}
+			for _, arg := range e.Args {
+				args = append(args, configValue(arg, src))
+			}
+			return terraform.FunctionCallVal(e.Name, args...)
+		}
+	}
+
+	v, diags := expr.Value(verifyContext)
+	if diags.HasErrors() {
+		return terraform.ExpressionVal(strings.TrimSpace(string(expr.Range().SliceBytes(src))))
+	}
+	return v
+}
+
+// yamlConfigValue reads the manifest from the YAML string or the
+// yamlencode() call of a backend that takes YAML, or from the YAML
+// passed to a decode function by --manifest-decode
+func yamlConfigValue(v cty.Value) (cty.Value, error) {
+	if terraform.IsFunctionCall(v) {
+		name, args := terraform.FunctionCallArgs(v)
+		switch {
+		case name == "yamlencode" && len(args) == 1:
+			return args[0], nil
+		case contains(decodeFunctions, name) && len(args) == 1:
+			v = args[0]
+		}
+	}
+	if v.Type() != cty.String || v.IsNull() {
+		return v, nil
+	}
+	manifests, err := readManifests(strings.NewReader(v.AsString()))
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if len(manifests) != 1 {
+		return cty.NilVal, fmt.Errorf("expected one YAML document but found %d", len(manifests))
+	}
+	return manifests[0], nil
+}
+
+// objectIdentity identifies the object a manifest creates as
+// apiVersion/kind/namespace/name, or "" if it has no name
+func objectIdentity(doc cty.Value) string {
+	name := getString(doc, "metadata", "name")
+	if name == "" {
+		return ""
+	}
+	namespace := getString(doc, "metadata", "namespace")
+	if ns, ok := getAttr(doc, "metadata", "namespace"); ok && terraform.IsExpression(ns) {
+		namespace = "${" + terraform.ExpressionString(ns) + "}"
+	}
+	return strings.Join([]string{getString(doc, "apiVersion"), getString(doc, "kind"), namespace, name}, "/")
+}
+
+// fieldDiff is a difference in a single field of a manifest
+type fieldDiff struct {
+	path string
+
+	// old is the value in the configuration and new the value in the
+	// manifests, a field that is only in one of them has only that value
+	old, new       cty.Value
+	hasOld, hasNew bool
+}
+
+// resourceDiff is a resource that was added, removed or changed
+type resourceDiff struct {
+	// action is + for resources only in the manifests, - for resources
+	// only in the configuration and ~ for resources that changed
+	action string
+
+	address string
+
+	// previous is the address in the configuration of a changed
+	// resource that was matched by the object it creates
+	previous string
+
+	fields []fieldDiff
+}
+
+// matchResources matches the resources written from the manifests with the
+// resources in an existing configuration, by address and then by the object
+// they create. It returns the index of the existing resource for each
+// resource, or -1 if there is none, and which existing resources matched.
+func matchResources(resources []resource, existing []existingResource) ([]int, []bool) {
+	matched := make([]int, len(resources))
+	used := make([]bool, len(existing))
+	for i, r := range resources {
+		matched[i] = -1
+		for j, e := range existing {
+			if !used[j] && e.name == r.name {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i, r := range resources {
+		id := objectIdentity(r.doc)
+		if matched[i] != -1 || id == "" {
+			continue
+		}
+		for j, e := range existing {
+			if !used[j] && objectIdentity(e.doc) == id {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	return matched, used
+}
+
+// diffResources compares the resources written from the manifests with the
+// resources in an existing configuration. Renamed resources are still
+// compared when they create the same object.
+func diffResources(resources []resource, existing []existingResource, o *options) []resourceDiff {
+	matched, used := matchResources(resources, existing)
+	diffs := []resourceDiff{}
+	for i, r := range resources {
+		address := fmt.Sprintf("%s.%s", o.backend.resourceType, r.name)
+		if matched[i] == -1 {
+			diffs = append(diffs, resourceDiff{action: "+", address: address})
+			continue
+		}
+		e := existing[matched[i]]
+		d := resourceDiff{action: "~", address: address}
+		if e.name != r.name {
+			d.previous = fmt.Sprintf("%s.%s", o.backend.resourceType, e.name)
+		}
+		diffValues(e.doc, r.doc, nil, &d.fields)
+		if d.previous != "" || len(d.fields) > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	for j, e := range existing {
+		if !used[j] {
+			diffs = append(diffs, resourceDiff{action: "-", address: fmt.Sprintf("%s.%s", o.backend.resourceType, e.name)})
+		}
+	}
+	return diffs
+}
+
+// diffValues adds the differences between the value in the configuration
+// and the value in the manifests to diffs, descending into objects and lists
+func diffValues(old, new cty.Value, path []pathStep, diffs *[]fieldDiff) {
+	oldTy, newTy := old.Type(), new.Type()
+	switch {
+	case old.IsNull() || new.IsNull():
+		if old.IsNull() && new.IsNull() {
+			return
+		}
+	case (oldTy.IsObjectType() || oldTy.IsMapType()) && (newTy.IsObjectType() || newTy.IsMapType()):
+		om, nm := valueMap(old), valueMap(new)
+		keys := []string{}
+		for key := range om {
+			keys = append(keys, key)
+		}
+		for key := range nm {
+			if _, ok := om[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			p := append(path[:len(path):len(path)], pathStep{attr: key})
+			o, hasOld := om[key]
+			n, hasNew := nm[key]
+			if hasOld && hasNew {
+				diffValues(o, n, p, diffs)
+				continue
+			}
+			*diffs = append(*diffs, fieldDiff{path: formatPath(p), old: o, new: n, hasOld: hasOld, hasNew: hasNew})
+		}
+		return
+	case (oldTy.IsTupleType() || oldTy.IsListType()) && (newTy.IsTupleType() || newTy.IsListType()):
+		oe, ne := old.AsValueSlice(), new.AsValueSlice()
+		for i := 0; i < len(oe) || i < len(ne); i++ {
+			p := append(path[:len(path):len(path)], pathStep{index: i})
+			switch {
+			case i >= len(oe):
+				*diffs = append(*diffs, fieldDiff{path: formatPath(p), new: ne[i], hasNew: true})
+			case i >= len(ne):
+				*diffs = append(*diffs, fieldDiff{path: formatPath(p), old: oe[i], hasOld: true})
+			default:
+				diffValues(oe[i], ne[i], p, diffs)
+			}
+		}
+		return
+	case terraform.IsExpression(old) || terraform.IsExpression(new):
+		// an expression such as file() is read back from the
+		// configuration as a function call, so compare the source
+		if diffString(old, 0) == diffString(new, 0) {
+			return
+		}
+	case terraform.IsFunctionCall(old) && terraform.IsFunctionCall(new):
+		oldName, oldArgs := terraform.FunctionCallArgs(old)
+		newName, newArgs := terraform.FunctionCallArgs(new)
+		if oldName == newName && len(oldArgs) == len(newArgs) {
+			argDiffs := []fieldDiff{}
+			for i := range oldArgs {
+				diffValues(oldArgs[i], newArgs[i], path, &argDiffs)
+			}
+			if len(argDiffs) == 0 {
+				return
+			}
+		}
+	case oldTy.IsPrimitiveType() && oldTy.Equals(newTy):
+		if old.Equals(new).True() {
+			return
+		}
+	}
+	*diffs = append(*diffs, fieldDiff{path: verifyPath(path), old: old, new: new, hasOld: true, hasNew: true})
+}
+
+// diffString formats a value in a difference, indenting the lines after the first
+func diffString(v cty.Value, indent int) string {
+	return terraform.FormatValueWithOptions(v, indent, terraform.FormatOptions{Heredoc: terraform.HeredocNever})
+}
+
+// formatDiff writes the differences in the style of a plan, ending
+// with a count of the resources that differ
+func formatDiff(diffs []resourceDiff) string {
+	if len(diffs) == 0 {
+		return "No differences.\n"
+	}
+
+	buf := strings.Builder{}
+	counts := map[string]int{}
+	for _, d := range diffs {
+		counts[d.action]++
+		if d.previous != "" {
+			fmt.Fprintf(&buf, "%s %s (was %s)\n", d.action, d.address, d.previous)
+		} else {
+			fmt.Fprintf(&buf, "%s %s\n", d.action, d.address)
+		}
+		for _, f := range d.fields {
+			switch {
+			case f.hasOld && f.hasNew:
+				fmt.Fprintf(&buf, "    ~ %s: %s -> %s\n", f.path, diffString(f.old, 6), diffString(f.new, 6))
+			case f.hasNew:
+				fmt.Fprintf(&buf, "    + %s: %s\n", f.path, diffString(f.new, 6))
+			default:
+				fmt.Fprintf(&buf, "    - %s: %s\n", f.path, diffString(f.old, 6))
+			}
+		}
+	}
+	fmt.Fprintf(&buf, "\n%d to add, %d to change, %d to remove.\n", counts["+"], counts["~"], counts["-"])
+	return buf.String()
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const diffYAML = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: web
+data:
+  mode: "0644"
+  added: "yes"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: test
+        image: nginx:1.25
+        args: ["--port", "8080"]
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: new
+`
+
+const diffConfig = `
+resource "kubernetes_manifest" "renamed" {
+  manifest = {
+    "apiVersion" = "v1"
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+      "namespace" = "web"
+    }
+    "data" = {
+      "mode" = "0644"
+      "removed" = "no"
+    }
+  }
+}
+
+resource "kubernetes_manifest" "deployment_test" {
+  manifest = {
+    apiVersion = "apps/v1"
+    kind = "Deployment"
+    metadata = {
+      name = "test"
+    }
+    spec = {
+      replicas = var.replicas
+      template = {
+        spec = {
+          containers = [
+            {
+              name = "test"
+              image = "nginx:1.24"
+              args = ["--port", "8080", "--debug"]
+            },
+          ]
+        }
+      }
+    }
+  }
+}
+
+resource "kubernetes_manifest" "secret_old" {
+  manifest = {
+    "apiVersion" = "v1"
+    "kind" = "Secret"
+    "metadata" = {
+      "name" = "old"
+    }
+  }
+}
+
+resource "kubernetes_config_map" "ignored" {
+}
+`
+
+func TestDiff(t *testing.T) {
+	o := newOptions("", false, false, false, nil)
+	resources, err := convertManifests(strings.NewReader(diffYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := parseConfiguration([]byte(diffConfig), "main.tf", o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `~ kubernetes_manifest.configmap_web_test (was kubernetes_manifest.renamed)
+    + data.added: "yes"
+    - data.removed: "no"
+~ kubernetes_manifest.deployment_test
+    ~ spec.replicas: var.replicas -> 3
+    - spec.template.spec.containers[0].args[2]: "--debug"
+    ~ spec.template.spec.containers[0].image: "nginx:1.24" -> "nginx:1.25"
++ kubernetes_manifest.service_new
+- kubernetes_manifest.secret_old
+
+1 to add, 2 to change, 1 to remove.
+`
+
+	assert.Equal(t, expected, formatDiff(diffResources(resources, existing, o)))
+}
+
+func TestDiffNoDifferences(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "manifest"},
+		{name: "function calls", opts: []Option{WithDecodedSecrets(), WithStructuredData()}},
+		{name: "manifest decode", opts: []Option{WithManifestDecode(), WithTarget(target{toolTerraform, version{1, 8}})}},
+		{name: "yamldecode", opts: []Option{WithManifestDecode()}},
+		{name: "kubectl", opts: []Option{WithBackend(backendKubectl)}},
+		{name: "kubectl expressions", opts: []Option{WithBackend(backendKubectl), WithNamespaceExpression("var.namespace")}},
+		{name: "k8s", opts: []Option{WithBackend(backendK8s)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOptions("", false, false, true, tt.opts)
+			resources, err := convertManifests(strings.NewReader(diffYAML), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := formatConfig(resources, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			err = os.WriteFile(filepath.Join(dir, "main.tf"), []byte(config), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			existing, err := readConfiguration(dir, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "No differences.\n", formatDiff(diffResources(resources, existing, o)))
+		})
+	}
+}
+
+func TestDiffExpressionsNoDifferences(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nginx
+data:
+  nginx.conf: |
+    server {
+      listen 80;
+    }
+  replicas: "3"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: tls
+data:
+  tls.key: c2VjcmV0IGtleQo=
+`
+	// file() and base64encode(file()) are written as expressions, but
+	// they are read back from the configuration as function calls
+	replicas, err := parseSetExpr(`data.replicas=tostring(var.replicas)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := newOptions("", false, false, false, []Option{
+		WithExternalizeThreshold(8), WithDecodedSecrets(), WithSetExpressions(replicas),
+	})
+	resources, err := convertManifests(strings.NewReader(yaml), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := formatConfig(resources, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(config, `base64encode(file(`) || !strings.Contains(config, `tostring(var.replicas)`) {
+		t.Fatalf("the output has no expressions:\n%s", config)
+	}
+
+	existing, err := parseConfiguration([]byte(config), "main.tf", o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "No differences.\n", formatDiff(diffResources(resources, existing, o)))
+}
+
+func TestReadConfigurationDuplicate(t *testing.T) {
+	o := newOptions("", false, false, false, nil)
+	dir := t.TempDir()
+	for _, name := range []string{"a.tf", "b.tf"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(diffConfig), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := readConfiguration(dir, o)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "kubernetes_manifest.renamed is in both")
+	}
+}
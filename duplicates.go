@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// duplicateStrategy is how resources that end up with the
+// same Terraform resource name are handled
+type duplicateStrategy string
+
+const (
+	// duplicateError fails the conversion
+	duplicateError duplicateStrategy = "error"
+
+	// duplicateSuffix adds a numeric suffix to every clashing name after the first
+	duplicateSuffix duplicateStrategy = "suffix"
+
+	// duplicateGroup adds the API group to every clashing name
+	duplicateGroup duplicateStrategy = "group"
+)
+
+var duplicateStrategies = []duplicateStrategy{
+	duplicateError,
+	duplicateSuffix,
+	duplicateGroup,
+}
+
+// parseDuplicateStrategy validates the value of the --duplicates flag
+func parseDuplicateStrategy(s string) (duplicateStrategy, error) {
+	for _, d := range duplicateStrategies {
+		if string(d) == s {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("unknown duplicate strategy %q, must be one of: error, suffix, group", s)
+}
+
+// identity returns the apiVersion/kind/namespace/name of a document
+func identity(doc cty.Value) string {
+	m := doc.AsValueMap()
+	var apiVersion, kind, namespace, name string
+	if v, ok := m["apiVersion"]; ok {
+		apiVersion = v.AsString()
+	}
+	if v, ok := m["kind"]; ok {
+		kind = v.AsString()
+	}
+	if v, ok := m["metadata"]; ok {
+		metadata := v.AsValueMap()
+		if v, ok := metadata["namespace"]; ok {
+			if terraform.IsExpression(v) {
+				namespace = terraform.ExpressionString(v)
+			} else {
+				namespace = v.AsString()
+			}
+		}
+		if v, ok := metadata["name"]; ok {
+			name = v.AsString()
+		} else if v, ok := metadata["generateName"]; ok {
+			name = v.AsString()
+		}
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return strings.Join([]string{apiVersion, kind, namespace, name}, "/")
+}
+
+// apiGroup returns the API group of a document, or "core" for the legacy group
+func apiGroup(doc cty.Value) string {
+	apiVersion := getString(doc, "apiVersion")
+	if i := strings.LastIndex(apiVersion, "/"); i != -1 {
+		return apiVersion[:i]
+	}
+	return "core"
+}
+
+// resolveDuplicates finds resources that share a Terraform resource name
+// and renames them according to the configured strategy. Documents that
+// share the same identity are reported as a warning as they are most
+// likely the result of a bad merge.
+func resolveDuplicates(resources []resource, o *options) ([]resource, error) {
+	seen := map[string]bool{}
+	for _, r := range resources {
+		id := identity(r.doc)
+		if seen[id] && o.namespace != cty.NilVal {
+			o.warnf("document %s appears more than once after overriding the namespace", id)
+		} else if seen[id] {
+			o.warnf("document %s appears more than once in the input", id)
+		}
+		seen[id] = true
+	}
+
+	byName := map[string][]int{}
+	for i, r := range resources {
+		byName[r.name] = append(byName[r.name], i)
+	}
+
+	taken := map[string]bool{}
+	for _, r := range resources {
+		taken[r.name] = true
+	}
+
+	for i, r := range resources {
+		clashes := byName[r.name]
+		if len(clashes) < 2 {
+			continue
+		}
+
+		switch o.duplicates {
+		case duplicateSuffix:
+			if clashes[0] == i {
+				continue
+			}
+			n := 2
+			name := fmt.Sprintf("%s_%d", r.name, n)
+			for taken[name] {
+				n++
+				name = fmt.Sprintf("%s_%d", r.name, n)
+			}
+			taken[name] = true
+			resources[i].name = name
+		case duplicateGroup:
+			kind := snakify(getString(r.doc, "kind"))
+			resources[i].name = kind + "_" + snakify(apiGroup(r.doc)) + strings.TrimPrefix(r.name, kind)
+		default:
+			if clashes[0] == i {
+				continue
+			}
+			first := resources[clashes[0]]
+			return nil, fmt.Errorf(
+				"documents %s and %s would both be written as resource %q, use --duplicates to resolve this",
+				identity(first.doc), identity(r.doc), r.name)
+		}
+	}
+
+	if o.duplicates == duplicateGroup {
+		// adding the group does not help when the names only differ by punctuation
+		names := map[string]resource{}
+		for _, r := range resources {
+			if first, ok := names[r.name]; ok {
+				return nil, fmt.Errorf(
+					"documents %s and %s would both be written as resource %q, try --duplicates=suffix",
+					identity(first.doc), identity(r.doc), r.name)
+			}
+			names[r.name] = r
+		}
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var duplicateNamesYAML = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my.app
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my_app_2`
+
+var duplicateGroupsYAML = `---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+---
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: web`
+
+func resourceNames(hcl string) []string {
+	names := []string{}
+	for _, line := range strings.Split(hcl, "\n") {
+		if strings.HasPrefix(line, "resource ") {
+			fields := strings.Fields(line)
+			names = append(names, strings.Trim(fields[2], `"`))
+		}
+	}
+	return names
+}
+
+func TestDuplicatesError(t *testing.T) {
+	r := strings.NewReader(duplicateNamesYAML)
+	_, err := YAMLToTerraformResources(r, "", false, false, false)
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `"configmap_my_app"`)
+		assert.Contains(t, err.Error(), "v1/ConfigMap/default/my-app")
+		assert.Contains(t, err.Error(), "v1/ConfigMap/default/my.app")
+	}
+}
+
+func TestDuplicatesSuffix(t *testing.T) {
+	r := strings.NewReader(duplicateNamesYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithDuplicateStrategy(duplicateSuffix))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	assert.Equal(t,
+		[]string{"configmap_my_app", "configmap_my_app_3", "configmap_my_app_2"},
+		resourceNames(output))
+}
+
+func TestDuplicatesGroup(t *testing.T) {
+	r := strings.NewReader(duplicateGroupsYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithDuplicateStrategy(duplicateGroup))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	assert.Equal(t,
+		[]string{"ingress_networking_k8s_io_web", "ingress_extensions_web"},
+		resourceNames(output))
+}
+
+func TestDuplicatesGroupWithoutAPIVersion(t *testing.T) {
+	r := strings.NewReader(`---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+---
+kind: Ingress
+metadata:
+  name: web`)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithDuplicateStrategy(duplicateGroup))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	assert.Equal(t,
+		[]string{"ingress_networking_k8s_io_web", "ingress_core_web"},
+		resourceNames(output))
+}
+
+func TestDuplicatesGroupStillClashing(t *testing.T) {
+	r := strings.NewReader(duplicateNamesYAML)
+	_, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithDuplicateStrategy(duplicateGroup))
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "--duplicates=suffix")
+	}
+}
+
+func TestDuplicatesIdentityWarning(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: default`
+
+	warnings := bytes.Buffer{}
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithDuplicateStrategy(duplicateSuffix), WithWarnings(&warnings))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	assert.Equal(t, []string{"configmap_test", "configmap_test_2"}, resourceNames(output))
+	assert.Equal(t,
+		"warning: document v1/ConfigMap/default/test appears more than once in the input\n",
+		warnings.String())
+}
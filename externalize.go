@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// externalizeDir is the directory, relative to the output,
+// that externalized values are written to
+const externalizeDir = "files"
+
+// dataKey matches the keys Kubernetes allows in ConfigMap and Secret data
+var dataKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// fileExpression returns an expression that reads a file relative to the module
+func fileExpression(function, filename string) cty.Value {
+	return terraform.ExpressionVal(fmt.Sprintf(`%s("${path.module}/%s")`, function, filename))
+}
+
+// externalizeFiles moves ConfigMap and Secret values that are larger than
+// the threshold into files next to the output. Text values are only moved
+// if they span multiple lines, binary and base64 encoded values are always
+// moved once they reach the threshold.
+func externalizeFiles(resources []resource, o *options) ([]resource, error) {
+	for i, r := range resources {
+		var fields map[string]bool
+		switch getString(r.doc, "kind") {
+		case "ConfigMap":
+			fields = map[string]bool{"data": false, "binaryData": true}
+		case "Secret":
+			fields = map[string]bool{"stringData": false, "data": true}
+		default:
+			continue
+		}
+
+		m := valueMap(r.doc)
+		for field, encoded := range fields {
+			v, ok := m[field]
+			if !ok || v.IsNull() || !v.Type().IsObjectType() {
+				continue
+			}
+
+			data := valueMap(v)
+			keys := []string{}
+			for k := range data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				value := data[k]
+				if value.Type() != cty.String || value.IsNull() {
+					continue
+				}
+
+				content := []byte(value.AsString())
+				function := "file"
+				if encoded {
+					decoded, err := base64.StdEncoding.DecodeString(value.AsString())
+					if err != nil {
+						return nil, fmt.Errorf("%s %s: %s.%s is not valid base64: %s",
+							getString(r.doc, "kind"), r.name, field, k, err)
+					}
+					content = decoded
+					function = "filebase64"
+				} else if !strings.Contains(value.AsString(), "\n") {
+					continue
+				}
+				if len(content) < o.externalizeThreshold {
+					continue
+				}
+
+				// the key becomes a file name, so it must not leave the directory
+				if !dataKey.MatchString(k) || k == "." || k == ".." {
+					return nil, fmt.Errorf("%s %s: %s key %q is not a valid ConfigMap or Secret key",
+						getString(r.doc, "kind"), r.name, field, k)
+				}
+				filename := path.Join(externalizeDir, r.name, k)
+				if r.files == nil {
+					r.files = map[string][]byte{}
+				}
+				r.files[filename] = content
+				if function == "filebase64" && o.decodeSecrets && getString(r.doc, "kind") == "Secret" && readable(content) {
+					data[k] = terraform.FunctionCallVal("base64encode", fileExpression("file", filename))
+				} else {
+					data[k] = fileExpression(function, filename)
+				}
+			}
+			m[field] = cty.ObjectVal(data)
+		}
+		r.doc = cty.ObjectVal(m)
+		resources[i] = r
+	}
+	return resources, nil
+}
+
+// writeFiles writes the externalized files of each resource
+// into the directory containing the output
+func writeFiles(dir string, resources []resource) error {
+	for _, r := range resources {
+		for name, content := range r.files {
+			filename := filepath.Join(dir, filepath.FromSlash(name))
+			err := os.MkdirAll(filepath.Dir(filename), 0755)
+			if err != nil {
+				return err
+			}
+			err = writeFile(filename, content)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
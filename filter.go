@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+)
+
+var (
+	// labelKey matches a label key with an optional DNS subdomain prefix
+	labelKey = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+	// labelValue matches a label value, which may be empty
+	labelValue = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+
+	// setRequirement matches the set-based requirements key in (a,b)
+	// and key notin (a,b)
+	setRequirement = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// selectorOperator is how a requirement compares a label to its values
+type selectorOperator string
+
+const (
+	selectorEquals       selectorOperator = "="
+	selectorNotEquals    selectorOperator = "!="
+	selectorIn           selectorOperator = "in"
+	selectorNotIn        selectorOperator = "notin"
+	selectorExists       selectorOperator = "exists"
+	selectorDoesNotExist selectorOperator = "!"
+)
+
+// requirement is a single condition of a label selector
+type requirement struct {
+	key      string
+	operator selectorOperator
+	values   []string
+}
+
+// selector is a Kubernetes label selector, which matches
+// the labels that meet all of its requirements
+type selector []requirement
+
+// parseSelector parses a label selector such as
+// app=web,tier!=cache,env in (prod,staging),!canary
+func parseSelector(s string) (selector, error) {
+	sel := selector{}
+	for _, part := range splitSelector(s) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty requirement", s)
+		}
+
+		r := requirement{}
+		if m := setRequirement.FindStringSubmatch(part); m != nil {
+			r.key, r.operator = m[1], selectorOperator(m[2])
+			for _, v := range strings.Split(m[3], ",") {
+				r.values = append(r.values, strings.TrimSpace(v))
+			}
+		} else if strings.HasPrefix(part, "!") {
+			r.key, r.operator = strings.TrimSpace(part[1:]), selectorDoesNotExist
+		} else if i := strings.Index(part, "!="); i != -1 {
+			r.key, r.operator, r.values = part[:i], selectorNotEquals, []string{part[i+2:]}
+		} else if i := strings.Index(part, "=="); i != -1 {
+			r.key, r.operator, r.values = part[:i], selectorEquals, []string{part[i+2:]}
+		} else if i := strings.Index(part, "="); i != -1 {
+			r.key, r.operator, r.values = part[:i], selectorEquals, []string{part[i+1:]}
+		} else {
+			r.key, r.operator = part, selectorExists
+		}
+
+		r.key = strings.TrimSpace(r.key)
+		if !labelKey.MatchString(r.key) {
+			return nil, fmt.Errorf("invalid selector %q: %q is not a valid label key", s, r.key)
+		}
+		for i, v := range r.values {
+			r.values[i] = strings.TrimSpace(v)
+			if !labelValue.MatchString(r.values[i]) {
+				return nil, fmt.Errorf("invalid selector %q: %q is not a valid label value", s, r.values[i])
+			}
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// splitSelector splits a selector into requirements at the
+// commas that aren't inside the parentheses of a set
+func splitSelector(s string) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// matches returns true if the labels meet every requirement
+func (sel selector) matches(labels map[string]string) bool {
+	for _, r := range sel {
+		value, ok := labels[r.key]
+		switch r.operator {
+		case selectorEquals, selectorIn:
+			if !ok || !contains(r.values, value) {
+				return false
+			}
+		case selectorNotEquals, selectorNotIn:
+			if ok && contains(r.values, value) {
+				return false
+			}
+		case selectorExists:
+			if !ok {
+				return false
+			}
+		case selectorDoesNotExist:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// documentLabels returns the labels of a document
+func documentLabels(doc cty.Value) map[string]string {
+	labels := map[string]string{}
+	m, _ := getAttr(doc, "metadata", "labels")
+	for k, v := range valueMap(m) {
+		if v.Type() == cty.String && !v.IsNull() {
+			labels[k] = v.AsString()
+		}
+	}
+	return labels
+}
+
+// includeDocument returns true if the document passes the kind,
+// namespace and label filters
+func includeDocument(doc cty.Value, o *options) bool {
+	kind := getString(doc, "kind")
+	if len(o.includeKinds) > 0 && !containsFold(o.includeKinds, kind) {
+		return false
+	}
+	if containsFold(o.excludeKinds, kind) {
+		return false
+	}
+	if len(o.namespaceFilter) > 0 {
+		// namespaced objects without a namespace are in the default namespace
+		namespace := getString(doc, "metadata", "namespace")
+		if namespace == "" && !contains(clusterScopedKinds, kind) && !contains(o.clusterScopedKinds, kind) {
+			namespace = "default"
+		}
+		if !contains(o.namespaceFilter, namespace) {
+			return false
+		}
+	}
+	if o.selector != nil && !o.selector.matches(documentLabels(doc)) {
+		return false
+	}
+	return true
+}
+
+// containsFold returns true if the string is in the list, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const filterYAML = `---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+    namespace: prod
+    labels:
+      app: web
+      env: prod
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: web
+    namespace: prod
+    labels:
+      app: web
+      env: prod
+      canary: "true"
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: web
+    namespace: staging
+    labels:
+      app: web
+      env: staging
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: db
+  namespace: prod
+  labels:
+    app: db
+    env: prod
+`
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		selector string
+		expected []string
+	}{
+		{
+			name:     "include kinds",
+			opts:     []Option{WithKindFilter([]string{"deployment", "Namespace"}, nil)},
+			expected: []string{"deployment_prod_web", "namespace_prod", "deployment_prod_db"},
+		},
+		{
+			name:     "exclude kinds",
+			opts:     []Option{WithKindFilter(nil, []string{"Deployment", "namespace"})},
+			expected: []string{"service_prod_web", "configmap_staging_web"},
+		},
+		{
+			name:     "namespaces",
+			opts:     []Option{WithNamespaceFilter("staging")},
+			expected: []string{"configmap_staging_web"},
+		},
+		{
+			name:     "equality",
+			selector: "app=web,env!=staging",
+			expected: []string{"deployment_prod_web", "service_prod_web"},
+		},
+		{
+			name:     "set based",
+			selector: "app in (web, db),env notin (staging),!canary",
+			expected: []string{"deployment_prod_web", "deployment_prod_db"},
+		},
+		{
+			name:     "exists",
+			selector: "canary",
+			expected: []string{"service_prod_web"},
+		},
+		{
+			name:     "combined",
+			opts:     []Option{WithKindFilter([]string{"Deployment"}, nil), WithNamespaceFilter("prod")},
+			selector: "app==db",
+			expected: []string{"deployment_prod_db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if tt.selector != "" {
+				sel, err := parseSelector(tt.selector)
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts = append(opts, WithSelector(sel))
+			}
+			o := newOptions("", false, false, false, opts)
+			resources, err := convertManifests(strings.NewReader(filterYAML), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, r := range resources {
+				names = append(names, r.name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestFilterDefaultNamespace(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+  namespace: default
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+`
+	o := newOptions("", false, false, false, []Option{WithNamespaceFilter("default")})
+	resources, err := convertManifests(strings.NewReader(yaml), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, r := range resources {
+		names = append(names, r.name)
+	}
+
+	// namespaced objects without a namespace are in the default
+	// namespace, cluster-scoped objects aren't in any
+	assert.Equal(t, []string{"configmap_settings", "configmap_web"}, names)
+}
+
+func TestParseSelector(t *testing.T) {
+	sel, err := parseSelector("app.kubernetes.io/name = web, tier!=cache,env in (prod,staging), release notin (), !canary, ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, selector{
+		{key: "app.kubernetes.io/name", operator: selectorEquals, values: []string{"web"}},
+		{key: "tier", operator: selectorNotEquals, values: []string{"cache"}},
+		{key: "env", operator: selectorIn, values: []string{"prod", "staging"}},
+		{key: "release", operator: selectorNotIn, values: []string{""}},
+		{key: "canary", operator: selectorDoesNotExist},
+		{key: "ready", operator: selectorExists},
+	}, sel)
+
+	for s, expected := range map[string]string{
+		"app=web,":     `invalid selector "app=web,": empty requirement`,
+		"app=we b":     `invalid selector "app=we b": "we b" is not a valid label value`,
+		"-app=web":     `invalid selector "-app=web": "-app" is not a valid label key`,
+		"env in prod":  `invalid selector "env in prod": "env in prod" is not a valid label key`,
+		"!app=web":     `invalid selector "!app=web": "app=web" is not a valid label key`,
+		"a in (b,c d)": `invalid selector "a in (b,c d)": "c d" is not a valid label value`,
+	} {
+		_, err := parseSelector(s)
+		if assert.Error(t, err, s) {
+			assert.Equal(t, expected, err.Error())
+		}
+	}
+}
@@ -3,17 +3,26 @@ module github.com/jrhouston/tfk8s
 go 1.19
 
 require (
+	github.com/fsnotify/fsnotify v1.6.0
+	github.com/hashicorp/hcl/v2 v2.20.1
 	github.com/spf13/pflag v1.0.3
 	github.com/stretchr/testify v1.5.1
-	github.com/zclconf/go-cty v1.8.0
+	github.com/zclconf/go-cty v1.13.1
+	github.com/zclconf/go-cty-yaml v1.1.0
+	gopkg.in/yaml.v2 v2.2.8
+	gopkg.in/yaml.v3 v3.0.1
 	sigs.k8s.io/yaml v1.1.0
 )
 
 require (
+	github.com/agext/levenshtein v1.2.1 // indirect
+	github.com/apparentlymart/go-textseg/v13 v13.0.0 // indirect
+	github.com/apparentlymart/go-textseg/v15 v15.0.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/google/go-cmp v0.5.2 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/text v0.3.8 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
-	gopkg.in/yaml.v2 v2.2.8 // indirect
 )
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// namespaceVariable matches a reference to the namespace variable
+var namespaceVariable = regexp.MustCompile(`\bvar\.namespace\b`)
+
+// importID is the id kubernetes_manifest imports an object by
+type importID struct {
+	// hcl is the id as an HCL string, which may interpolate expressions
+	hcl string
+
+	// raw is the id as it would be passed on the command line
+	raw string
+
+	// expression is true if the id interpolates an expression
+	expression bool
+}
+
+// resourceImportID builds the import id of a document in the form
+// apiVersion=v1,kind=ConfigMap,namespace=default,name=example. When
+// namespace is not empty it replaces a namespace set with an expression.
+func resourceImportID(doc cty.Value, namespace string) (importID, bool) {
+	name := getString(doc, "metadata", "name")
+	if name == "" {
+		return importID{}, false
+	}
+
+	id := importID{}
+	add := func(key, value string) {
+		if id.raw != "" {
+			id.raw += ","
+			id.hcl += ","
+		}
+		literal := quote(value)
+		id.raw += key + "=" + value
+		id.hcl += key + "=" + literal[1:len(literal)-1]
+	}
+	add("apiVersion", getString(doc, "apiVersion"))
+	add("kind", getString(doc, "kind"))
+	if ns, ok := getAttr(doc, "metadata", "namespace"); ok && terraform.IsExpression(ns) {
+		if namespace != "" {
+			add("namespace", namespace)
+		} else {
+			expr := terraform.ExpressionString(ns)
+			id.raw += ",namespace=${" + expr + "}"
+			id.hcl += ",namespace=${" + expr + "}"
+			id.expression = true
+		}
+	} else if ns := getString(doc, "metadata", "namespace"); ns != "" {
+		add("namespace", ns)
+	}
+	add("name", name)
+	id.hcl = `"` + id.hcl + `"`
+	return id, true
+}
+
+// formatImports writes an import block for each resource, or import commands
+// in a comment for those the target can't import with a block. The module
+// prefix is added to the resource addresses and namespace replaces a
+// namespace expression, for root modules that call a module.
+func formatImports(resources []resource, o *options, module, namespace string) string {
+	blocks := []string{}
+	commands := []string{}
+	for _, r := range resources {
+		address := fmt.Sprintf("%s%s.%s", module, o.backend.resourceType, r.name)
+		if o.backend.importID == nil {
+			o.warnf("%s can't be imported, %s does not support importing", address, o.backend.resourceType)
+			continue
+		}
+		id, ok := o.backend.importID(r.doc, namespace)
+		if !ok {
+			o.warnf("%s has no name so it can't be imported", address)
+			continue
+		}
+
+		required := featureImportBlocks
+		if id.expression {
+			required = featureImportExpressions
+		}
+		if !o.target.supports(required) {
+			o.warnf("%s does not support %s, writing an import command for %s instead", o.target, required.name, address)
+			commands = append(commands, fmt.Sprintf("#   %s import '%s' '%s'\n", o.target.command(), address, id.raw))
+			continue
+		}
+
+		blocks = append(blocks, "import {\n"+
+			alignAttributes(2, [][2]string{
+				{"to", address},
+				{"id", id.hcl},
+			})+
+			"}\n")
+	}
+
+	if len(commands) > 0 {
+		blocks = append(blocks, "# Import the existing objects with:\n"+strings.Join(commands, ""))
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// formatImportsForEach writes an import block with for_each over the
+// namespaces for each resource of a module that is called with for_each,
+// or an import block per namespace when the target can't import with
+// for_each
+func formatImportsForEach(resources []resource, o *options, module string, namespaces []string) string {
+	if !o.target.supports(featureImportForEach) {
+		o.warnf("%s does not support %s, writing an import block for each namespace instead", o.target, featureImportForEach.name)
+		blocks := []string{}
+		for _, ns := range namespaces {
+			if imports := formatImports(resources, o, fmt.Sprintf("module.%s[%s].", module, quote(ns)), ns); imports != "" {
+				blocks = append(blocks, imports)
+			}
+		}
+		return strings.Join(blocks, "\n")
+	}
+
+	blocks := []string{}
+	for _, r := range resources {
+		address := fmt.Sprintf("module.%s[each.key].%s.%s", module, o.backend.resourceType, r.name)
+		if o.backend.importID == nil {
+			o.warnf("%s can't be imported, %s does not support importing", address, o.backend.resourceType)
+			continue
+		}
+		id, ok := o.backend.importID(r.doc, "")
+		if !ok {
+			o.warnf("%s has no name so it can't be imported", address)
+			continue
+		}
+
+		blocks = append(blocks, "import {\n"+
+			alignAttributes(2, [][2]string{
+				{"for_each", namespaceSet(namespaces)},
+				{"to", address},
+				// the root module passes each.key as the namespace variable
+				{"id", namespaceVariable.ReplaceAllString(id.hcl, "each.key")},
+			})+
+			"}\n")
+	}
+	return strings.Join(blocks, "\n")
+}
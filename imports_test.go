@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const importsYAML = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: web
+data:
+  KEY: value
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: web`
+
+func TestImportBlocks(t *testing.T) {
+	o := newOptions("", false, false, false, []Option{WithImportBlocks()})
+	resources, err := convertManifests(strings.NewReader(importsYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `import {
+  to = kubernetes_manifest.configmap_web_test
+  id = "apiVersion=v1,kind=ConfigMap,namespace=web,name=test"
+}
+
+import {
+  to = kubernetes_manifest.namespace_web
+  id = "apiVersion=v1,kind=Namespace,name=web"
+}
+`
+	assert.Equal(t, expected, formatImports(resources, o, "", ""))
+}
+
+func TestImportBlocksNamespaceExpression(t *testing.T) {
+	target, err := parseTarget("terraform@1.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := newOptions("", false, false, false, []Option{
+		WithImportBlocks(),
+		WithTarget(target),
+		WithNamespaceExpression("var.namespace"),
+	})
+	resources, err := convertManifests(strings.NewReader(importsYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `import {
+  to = kubernetes_manifest.configmap_web_test
+  id = "apiVersion=v1,kind=ConfigMap,namespace=${var.namespace},name=test"
+}
+
+import {
+  to = kubernetes_manifest.namespace_web
+  id = "apiVersion=v1,kind=Namespace,name=web"
+}
+`
+	assert.Equal(t, expected, formatImports(resources, o, "", ""))
+
+	expected = `import {
+  to = module.web.kubernetes_manifest.configmap_web_test
+  id = "apiVersion=v1,kind=ConfigMap,namespace=staging,name=test"
+}
+`
+	assert.Equal(t, expected, formatImports(resources[:1], o, "module.web.", "staging"))
+}
+
+func TestImportCommands(t *testing.T) {
+	target, err := parseTarget("opentofu@1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := bytes.Buffer{}
+	r := strings.NewReader(importsYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithImportBlocks(), WithTarget(target), WithWarnings(&warnings))
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	assert.True(t, strings.HasSuffix(output, `
+# Import the existing objects with:
+#   tofu import 'kubernetes_manifest.configmap_web_test' 'apiVersion=v1,kind=ConfigMap,namespace=web,name=test'
+#   tofu import 'kubernetes_manifest.namespace_web' 'apiVersion=v1,kind=Namespace,name=web'
+`), output)
+	assert.Contains(t, warnings.String(), "opentofu@1.5 does not support import blocks")
+}
+
+func TestImportBlocksForEach(t *testing.T) {
+	for _, tc := range []struct {
+		target   string
+		expected string
+		warning  string
+	}{
+		{"terraform@1.7", `import {
+  for_each = toset(["dev", "prod"])
+  to       = module.web[each.key].kubernetes_manifest.configmap_web_test
+  id       = "apiVersion=v1,kind=ConfigMap,namespace=${each.key},name=test"
+}
+`, ""},
+		{"opentofu@1.6", `import {
+  to = module.web["dev"].kubernetes_manifest.configmap_web_test
+  id = "apiVersion=v1,kind=ConfigMap,namespace=dev,name=test"
+}
+
+import {
+  to = module.web["prod"].kubernetes_manifest.configmap_web_test
+  id = "apiVersion=v1,kind=ConfigMap,namespace=prod,name=test"
+}
+`, "opentofu@1.6 does not support import blocks with for_each"},
+	} {
+		t.Run(tc.target, func(t *testing.T) {
+			target, err := parseTarget(tc.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings := bytes.Buffer{}
+			o := newOptions("", false, false, false, []Option{
+				WithImportBlocks(),
+				WithTarget(target),
+				WithNamespaceExpression("var.namespace"),
+				WithModuleNamespaces("dev", "prod"),
+				WithModuleForEach(),
+				WithWarnings(&warnings),
+			})
+			resources, err := convertManifests(strings.NewReader(importsYAML), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tc.expected, formatImportsForEach(resources[:1], o, "web", o.moduleNamespaces))
+			if tc.warning == "" {
+				assert.Empty(t, warnings.String())
+			} else {
+				assert.Contains(t, warnings.String(), tc.warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// jsonInput reports whether the input starts like JSON, returning
+// a reader that still holds everything that was looked at
+func jsonInput(r io.Reader) (io.Reader, bool) {
+	br := bufio.NewReader(r)
+	for n := 1; n <= br.Size(); n++ {
+		b, err := br.Peek(n)
+		if len(b) < n || err != nil {
+			break
+		}
+		switch b[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return br, true
+		}
+		break
+	}
+	return br, false
+}
+
+// replayWriter keeps what is written to it until it is stopped
+type replayWriter struct {
+	buf     bytes.Buffer
+	stopped bool
+}
+
+func (w *replayWriter) Write(p []byte) (int, error) {
+	if !w.stopped {
+		w.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// readManifestsJSON decodes a stream of JSON values, such as the output of
+// kubectl get -o json --watch or jq -c, one at a time. Arrays of objects are
+// read as one manifest per element. If the first value isn't valid JSON the
+// input is read as YAML instead, since YAML flow mappings also start with {.
+// Input that stops being JSON later on, such as JSON documents separated
+// with ---, is read one document at a time from there.
+func readManifestsJSON(r io.Reader) ([]cty.Value, error) {
+	replay := &replayWriter{}
+	dec := json.NewDecoder(io.TeeReader(r, replay))
+	manifests := []cty.Value{}
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			if !replay.stopped {
+				return readManifests(io.MultiReader(&replay.buf, r))
+			}
+			// the decoder still holds what it read after the last value
+			docs, err := readDocuments(io.MultiReader(dec.Buffered(), r))
+			if err != nil {
+				return nil, err
+			}
+			return append(manifests, docs...), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		replay.stopped = true
+		replay.buf = bytes.Buffer{}
+
+		docs, err := jsonManifests(raw)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, docs...)
+	}
+	return manifests, nil
+}
+
+// readDocuments splits a stream on the --- separator and reads it
+// one document at a time
+func readDocuments(r io.Reader) ([]cty.Value, error) {
+	br := bufio.NewReader(r)
+	manifests := []cty.Value{}
+	doc := strings.Builder{}
+	flush := func() error {
+		docs, err := readDocument(doc.String())
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, docs...)
+		doc.Reset()
+		return nil
+	}
+	for {
+		line, err := br.ReadString('\n')
+		if strings.HasPrefix(line, "---") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			line = strings.TrimPrefix(line, "---")
+		}
+		doc.WriteString(line)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return manifests, nil
+}
+
+// readDocument reads a single document, which can hold a stream
+// of JSON values or YAML
+func readDocument(doc string) ([]cty.Value, error) {
+	dec := json.NewDecoder(strings.NewReader(doc))
+	manifests := []cty.Value{}
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return manifests, nil
+		}
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return readManifests(strings.NewReader(doc))
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs, err := jsonManifests(raw)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, docs...)
+	}
+}
+
+// jsonManifests converts a JSON value to manifests
+func jsonManifests(raw json.RawMessage) ([]cty.Value, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return nil, err
+		}
+		manifests := []cty.Value{}
+		for _, el := range elems {
+			docs, err := jsonManifests(el)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, docs...)
+		}
+		return manifests, nil
+	}
+
+	t, err := ctyjson.ImpliedType(raw)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ctyjson.Unmarshal(raw, t)
+	if err != nil {
+		return nil, err
+	}
+	if doc.IsNull() {
+		return nil, nil
+	}
+	if !doc.Type().IsObjectType() {
+		return nil, fmt.Errorf("the manifest must be a JSON object")
+	}
+	return []cty.Value{doc}, nil
+}
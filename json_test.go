@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONStream(t *testing.T) {
+	// kubectl get -o json --watch writes pretty printed objects one after
+	// another, jq -c writes one object per line
+	tests := map[string]string{
+		"concatenated": `{
+  "apiVersion": "v1",
+  "kind": "ConfigMap",
+  "metadata": {"name": "a"}
+}
+{
+  "apiVersion": "v1",
+  "kind": "ConfigMap",
+  "metadata": {"name": "b"}
+}
+`,
+		"ndjson": `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}
+{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"b"}}`,
+		"array": `  [{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}},
+   {"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"b"}}, null]`,
+		"separated": `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}
+---
+{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"b"}}
+`,
+		"yaml after json": `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+---
+`,
+		"nested lists": `{"apiVersion": "v1", "kind": "List", "items": [
+  {"apiVersion": "v1", "kind": "ConfigMapList", "items": [
+    {"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}}
+  ]},
+  {"apiVersion": "v1", "kind": "List", "items": []},
+  {"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"b"}}
+]}`,
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := newOptions("", false, false, false, nil)
+			resources, err := convertManifests(strings.NewReader(input), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, r := range resources {
+				names = append(names, r.name)
+			}
+			assert.Equal(t, []string{"configmap_a", "configmap_b"}, names)
+		})
+	}
+}
+
+func TestJSONDirectory(t *testing.T) {
+	// openInput joins the files of a directory with ---
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		doc := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"` + name + `"}}`
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(doc), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := openInput(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	o := newOptions("", false, false, false, nil)
+	resources, err := convertManifests(r, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, r := range resources {
+		names = append(names, r.name)
+	}
+	assert.Equal(t, []string{"configmap_a", "configmap_b"}, names)
+}
+
+func TestJSONNumbers(t *testing.T) {
+	r := strings.NewReader(`{"apiVersion": "v1", "kind": "Test", "metadata": {"name": "test"}, "spec": {"big": 123456789012345678901234567890, "small": 0.1}}`)
+	output, err := YAMLToTerraformResources(r, "", false, true, false)
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+{
+  "apiVersion" = "v1"
+  "kind" = "Test"
+  "metadata" = {
+    "name" = "test"
+  }
+  "spec" = {
+    "big" = 123456789012345678901234567890
+    "small" = 0.1
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestJSONFlowMapping(t *testing.T) {
+	// YAML flow mappings start like JSON
+	r := strings.NewReader("{apiVersion: v1, kind: ConfigMap, metadata: {name: test}}\n---\nkind: ConfigMap\nmetadata:\n  name: other\n")
+	o := newOptions("", false, false, false, nil)
+	resources, err := convertManifests(r, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, resources, 2) {
+		assert.Equal(t, "configmap_test", resources[0].name)
+		assert.Equal(t, "configmap_other", resources[1].name)
+	}
+}
+
+func TestJSONNotAnObject(t *testing.T) {
+	_, err := YAMLToTerraformResources(strings.NewReader(`{"kind": "ConfigMap"} "test"`), "", false, false, false)
+	if assert.Error(t, err) {
+		assert.Equal(t, "the manifest must be a JSON object", err.Error())
+	}
+}
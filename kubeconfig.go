@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+	yaml "sigs.k8s.io/yaml"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// kubeconfig is the part of a kubeconfig file needed to configure the provider
+type kubeconfig struct {
+	CurrentContext string `json:"current-context"`
+	Clusters       []struct {
+		Name    string `json:"name"`
+		Cluster struct {
+			Server                   string `json:"server"`
+			CertificateAuthority     string `json:"certificate-authority"`
+			CertificateAuthorityData string `json:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+			TLSServerName            string `json:"tls-server-name"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+	Contexts []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Cluster string `json:"cluster"`
+			User    string `json:"user"`
+		} `json:"context"`
+	} `json:"contexts"`
+	Users []struct {
+		Name string `json:"name"`
+		User struct {
+			Exec *kubeconfigExec `json:"exec"`
+		} `json:"user"`
+	} `json:"users"`
+}
+
+// kubeconfigExec is a credential plugin that the provider runs to get a token
+type kubeconfigExec struct {
+	APIVersion string   `json:"apiVersion"`
+	Command    string   `json:"command"`
+	Args       []string `json:"args"`
+	Env        []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"env"`
+}
+
+// kubernetesProvider is an aliased provider block for one kubeconfig context
+type kubernetesProvider struct {
+	alias string
+
+	// context and configPath are the kubeconfig context the provider
+	// uses, configPath is empty when the cluster is written inline
+	context    string
+	configPath string
+
+	// attrs are the arguments of the block, already formatted as HCL
+	attrs [][2]string
+
+	// exec is written as a nested exec block
+	exec *kubeconfigExec
+
+	// comment explains anything that still needs to be configured
+	comment string
+}
+
+// invalidIdentifierStart matches names that can't start an HCL identifier
+var invalidIdentifierStart = regexp.MustCompile(`^[^A-Za-z_]`)
+
+// providerAlias turns a context name into a provider alias
+func providerAlias(context string) string {
+	alias := snakify(context)
+	if invalidIdentifierStart.MatchString(alias) {
+		alias = "context_" + alias
+	}
+	return alias
+}
+
+// quote formats a string as an HCL string literal
+func quote(s string) string {
+	return terraform.FormatValue(cty.StringVal(s), 0, false)
+}
+
+// kubeconfigProviders reads the kubeconfig at path and returns a provider
+// for each of the contexts, or the current context if none are given.
+// When inline is set the providers have the host and CA certificate of
+// the cluster instead of pointing at the kubeconfig.
+func kubeconfigProviders(path string, contexts []string, inline bool) ([]kubernetesProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kc := kubeconfig{}
+	err = yaml.Unmarshal(b, &kc)
+	if err != nil {
+		return nil, fmt.Errorf("could not read kubeconfig %s: %s", path, err)
+	}
+
+	if len(contexts) == 0 {
+		if kc.CurrentContext == "" {
+			return nil, fmt.Errorf("kubeconfig %s has no current context, select one with --kube-context", path)
+		}
+		contexts = []string{kc.CurrentContext}
+	}
+
+	providers := []kubernetesProvider{}
+	aliases := map[string]string{}
+	for _, name := range contexts {
+		alias := providerAlias(name)
+		if other, ok := aliases[alias]; ok {
+			return nil, fmt.Errorf("contexts %q and %q would both use the provider alias %q", other, name, alias)
+		}
+		aliases[alias] = name
+
+		p := kubernetesProvider{alias: alias, context: name}
+		p.attrs = append(p.attrs, [2]string{"alias", quote(alias)})
+		if !inline {
+			if !kc.hasContext(name) {
+				return nil, fmt.Errorf("context %q not found in kubeconfig %s, available contexts are: %s",
+					name, path, strings.Join(kc.contextNames(), ", "))
+			}
+			p.configPath = path
+			p.attrs = append(p.attrs,
+				[2]string{"config_path", quote(path)},
+				[2]string{"config_context", quote(name)})
+		} else {
+			err := kc.inlineCluster(&p, name, filepath.Dir(path))
+			if err != nil {
+				return nil, fmt.Errorf("kubeconfig %s: %s", path, err)
+			}
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+func (kc kubeconfig) contextNames() []string {
+	names := []string{}
+	for _, c := range kc.Contexts {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (kc kubeconfig) hasContext(name string) bool {
+	return contains(kc.contextNames(), name)
+}
+
+// inlineCluster adds the host and CA certificate of the cluster of a context
+// to the provider, along with the credential plugin of its user if it has one
+func (kc kubeconfig) inlineCluster(p *kubernetesProvider, context, dir string) error {
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == context {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("context %q not found, available contexts are: %s",
+			context, strings.Join(kc.contextNames(), ", "))
+	}
+
+	found = false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		p.attrs = append(p.attrs, [2]string{"host", quote(c.Cluster.Server)})
+		switch {
+		case c.Cluster.CertificateAuthorityData != "":
+			p.attrs = append(p.attrs, [2]string{"cluster_ca_certificate",
+				fmt.Sprintf("base64decode(%s)", quote(c.Cluster.CertificateAuthorityData))})
+		case c.Cluster.CertificateAuthority != "":
+			ca := c.Cluster.CertificateAuthority
+			if !filepath.IsAbs(ca) {
+				// paths in a kubeconfig are relative to the kubeconfig
+				ca = filepath.Join(dir, ca)
+			}
+			p.attrs = append(p.attrs, [2]string{"cluster_ca_certificate",
+				fmt.Sprintf("file(%s)", quote(ca))})
+		}
+		if c.Cluster.InsecureSkipTLSVerify {
+			p.attrs = append(p.attrs, [2]string{"insecure", "true"})
+		}
+		if c.Cluster.TLSServerName != "" {
+			p.attrs = append(p.attrs, [2]string{"tls_server_name", quote(c.Cluster.TLSServerName)})
+		}
+	}
+	if !found {
+		return fmt.Errorf("cluster %q of context %q not found", clusterName, context)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name == userName && u.User.Exec != nil {
+			p.exec = u.User.Exec
+			return nil
+		}
+	}
+	// tokens and client certificates are secrets that don't belong in the config
+	p.comment = fmt.Sprintf("credentials for user %q of context %q need to be configured", userName, context)
+	return nil
+}
+
+// formatProvider writes out a provider block
+func formatProvider(p kubernetesProvider, o *options) string {
+	hcl := fmt.Sprintf("provider %q {\n", o.backend.provider)
+	if p.comment != "" {
+		hcl += "  # " + p.comment + "\n"
+	}
+	attrs := p.attrs
+	if p.configPath == "" {
+		attrs = append(attrs[:len(attrs):len(attrs)], o.backend.inlineProviderAttrs...)
+	}
+	hcl += alignAttributes(2, attrs)
+	if p.exec != nil {
+		attrs := [][2]string{
+			{"api_version", quote(p.exec.APIVersion)},
+			{"command", quote(p.exec.Command)},
+		}
+		if len(p.exec.Args) > 0 {
+			args := []string{}
+			for _, a := range p.exec.Args {
+				args = append(args, quote(a))
+			}
+			attrs = append(attrs, [2]string{"args", "[" + strings.Join(args, ", ") + "]"})
+		}
+		hcl += "\n  exec {\n" + alignAttributes(4, attrs)
+		if len(p.exec.Env) > 0 {
+			env := [][2]string{}
+			for _, e := range p.exec.Env {
+				env = append(env, [2]string{quote(e.Name), quote(e.Value)})
+			}
+			// terraform fmt does not align attributes with multi-line values
+			hcl += "    env = {\n" + alignAttributes(6, env) + "    }\n"
+		}
+		hcl += "  }\n"
+	}
+	hcl += "}\n"
+	return hcl
+}
+
+// iteratedProviderAlias is the alias of the provider block
+// that is repeated for each context with for_each
+const iteratedProviderAlias = "context"
+
+// iterateProviders returns true if the providers are written as a
+// single provider block with for_each, which needs them all to
+// point at the kubeconfig and a target that supports it
+func iterateProviders(o *options) bool {
+	if len(o.providers) < 2 || len(o.moduleNamespaces) > 0 || !o.target.supports(featureProviderIteration) {
+		return false
+	}
+	for _, p := range o.providers {
+		if p.configPath == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceProvider returns the provider argument of every resource
+func resourceProvider(o *options) string {
+	switch {
+	case iterateProviders(o):
+		return fmt.Sprintf("%s.%s[%s]", o.backend.provider, iteratedProviderAlias, quote(o.providers[0].context))
+	case len(o.providers) > 0:
+		return o.backend.provider + "." + o.providers[0].alias
+	}
+	return o.providerAlias
+}
+
+// formatProviders writes out all of the provider blocks
+func formatProviders(o *options) string {
+	if iterateProviders(o) {
+		contexts := []string{}
+		for _, p := range o.providers {
+			contexts = append(contexts, quote(p.context))
+		}
+		return fmt.Sprintf("provider %q {\n", o.backend.provider) +
+			alignAttributes(2, [][2]string{
+				{"for_each", "toset([" + strings.Join(contexts, ", ") + "])"},
+				{"alias", quote(iteratedProviderAlias)},
+				{"config_path", quote(o.providers[0].configPath)},
+				{"config_context", "each.key"},
+			}) +
+			"}\n"
+	}
+
+	blocks := []string{}
+	for _, p := range o.providers {
+		blocks = append(blocks, formatProvider(p, o))
+	}
+	return strings.Join(blocks, "\n")
+}
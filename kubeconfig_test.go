@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+)
+
+const eksContext = "arn:aws:eks:us-east-1:123456789012:cluster/production"
+
+func TestKubeconfigProviders(t *testing.T) {
+	providers, err := kubeconfigProviders("testdata/kubeconfig.yaml", nil, false)
+	if err != nil {
+		t.Fatal("Reading the kubeconfig failed:", err)
+	}
+
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  KEY: value`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false, WithKubeconfigProviders(providers...))
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+provider "kubernetes" {
+  alias          = "staging"
+  config_path    = "testdata/kubeconfig.yaml"
+  config_context = "staging"
+}
+
+resource "kubernetes_manifest" "configmap_test" {
+  provider = kubernetes.staging
+
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      "KEY" = "value"
+    }
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+    }
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestKubeconfigProvidersInline(t *testing.T) {
+	providers, err := kubeconfigProviders("testdata/kubeconfig.yaml", []string{eksContext, "staging"}, true)
+	if err != nil {
+		t.Fatal("Reading the kubeconfig failed:", err)
+	}
+
+	expected := `provider "kubernetes" {
+  alias                  = "arn_aws_eks_us_east_1_123456789012_cluster_production"
+  host                   = "https://production.example.com"
+  cluster_ca_certificate = file("testdata/certs/production-ca.crt")
+  tls_server_name        = "kubernetes.production"
+
+  exec {
+    api_version = "client.authentication.k8s.io/v1beta1"
+    command     = "aws"
+    args        = ["eks", "get-token", "--cluster-name", "production"]
+    env = {
+      "AWS_PROFILE" = "production"
+    }
+  }
+}
+
+provider "kubernetes" {
+  # credentials for user "staging-admin" of context "staging" need to be configured
+  alias                  = "staging"
+  host                   = "https://staging.example.com:6443"
+  cluster_ca_certificate = base64decode("LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0tCg==")
+}
+`
+
+	output := formatProviders(newOptions("", false, false, false, []Option{WithKubeconfigProviders(providers...)}))
+	assert.Equal(t, expected, output)
+	assert.Equal(t, output, string(hclwrite.Format([]byte(output))))
+}
+
+func TestKubeconfigProvidersUnknownContext(t *testing.T) {
+	_, err := kubeconfigProviders("testdata/kubeconfig.yaml", []string{"missing"}, false)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), eksContext+", staging")
+	}
+}
+
+func TestProviderAlias(t *testing.T) {
+	assert.Equal(t, "kind_dev", providerAlias("kind-dev"))
+	assert.Equal(t, "context_1_cluster", providerAlias("1-cluster"))
+}
+
+func TestKubeconfigProvidersIteration(t *testing.T) {
+	providers, err := kubeconfigProviders("testdata/kubeconfig.yaml", []string{"staging", eksContext}, false)
+	if err != nil {
+		t.Fatal("Reading the kubeconfig failed:", err)
+	}
+	target, err := parseTarget("opentofu@1.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := newOptions("", false, false, false, []Option{WithKubeconfigProviders(providers...), WithTarget(target)})
+
+	expected := `provider "kubernetes" {
+  for_each       = toset(["staging", "arn:aws:eks:us-east-1:123456789012:cluster/production"])
+  alias          = "context"
+  config_path    = "testdata/kubeconfig.yaml"
+  config_context = each.key
+}
+`
+	assert.Equal(t, expected, formatProviders(o))
+	assert.Equal(t, `kubernetes.context["staging"]`, resourceProvider(o))
+
+	o = newOptions("", false, false, false, []Option{WithKubeconfigProviders(providers...)})
+	assert.Equal(t, "kubernetes.staging", resourceProvider(o))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+)
+
+// podTemplatePaths are the locations of pod templates in workload kinds
+var podTemplatePaths = map[string][]string{
+	"DaemonSet":             {"spec", "template"},
+	"Deployment":            {"spec", "template"},
+	"Job":                   {"spec", "template"},
+	"PodTemplate":           {"template"},
+	"ReplicaSet":            {"spec", "template"},
+	"ReplicationController": {"spec", "template"},
+	"StatefulSet":           {"spec", "template"},
+}
+
+// selectorPaths are the locations of the label selectors of workload kinds.
+// Jobs are not included as their selectors are generated by the API server.
+var selectorPaths = map[string][]string{
+	"DaemonSet":             {"spec", "selector", "matchLabels"},
+	"Deployment":            {"spec", "selector", "matchLabels"},
+	"ReplicaSet":            {"spec", "selector", "matchLabels"},
+	"ReplicationController": {"spec", "selector"},
+	"StatefulSet":           {"spec", "selector", "matchLabels"},
+}
+
+// parseKeyValues parses a list of key=value strings into a map
+func parseKeyValues(kvs []string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, kv := range kvs {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("%q must be in the form key=value", kv)
+		}
+		m[parts[0]] = parts[1]
+	}
+	return m, nil
+}
+
+// mergeStrings adds the key value pairs to an object of strings,
+// replacing any values that are already there
+func mergeStrings(add map[string]string) func(cty.Value) cty.Value {
+	return func(v cty.Value) cty.Value {
+		m := map[string]cty.Value{}
+		if v != cty.NilVal {
+			m = valueMap(v)
+		}
+		keys := []string{}
+		for k := range add {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			m[k] = cty.StringVal(add[k])
+		}
+		return cty.ObjectVal(m)
+	}
+}
+
+// addMetadata merges the labels and annotations into the metadata
+// of the object at path
+func addMetadata(doc cty.Value, labels, annotations map[string]string, path ...string) cty.Value {
+	metadata := append(append([]string{}, path...), "metadata")
+	if len(labels) > 0 {
+		doc = updateAttr(doc, true, mergeStrings(labels), append(metadata, "labels")...)
+	}
+	if len(annotations) > 0 {
+		doc = updateAttr(doc, true, mergeStrings(annotations), append(metadata, "annotations")...)
+	}
+	return doc
+}
+
+// addCommonMetadata adds the --label and --annotation values to every
+// document, and optionally to pod templates and workload selectors
+func addCommonMetadata(resources []resource, o *options) []resource {
+	for i, r := range resources {
+		doc := addMetadata(r.doc, o.labels, o.annotations)
+
+		kind := getString(doc, "kind")
+		if o.templateMetadata || o.selectorLabels {
+			if path, ok := podTemplatePaths[kind]; ok {
+				if _, ok := getAttr(doc, path...); ok {
+					doc = addMetadata(doc, o.labels, o.annotations, path...)
+				}
+			}
+			if kind == "CronJob" {
+				if _, ok := getAttr(doc, "spec", "jobTemplate"); ok {
+					doc = addMetadata(doc, o.labels, o.annotations, "spec", "jobTemplate")
+				}
+				if _, ok := getAttr(doc, "spec", "jobTemplate", "spec", "template"); ok {
+					doc = addMetadata(doc, o.labels, o.annotations, "spec", "jobTemplate", "spec", "template")
+				}
+			}
+		}
+		if o.selectorLabels && len(o.labels) > 0 {
+			if path, ok := selectorPaths[kind]; ok {
+				if _, ok := getAttr(doc, path[:len(path)-1]...); ok {
+					doc = updateAttr(doc, true, mergeStrings(o.labels), path...)
+				}
+			}
+		}
+
+		resources[i].doc = doc
+	}
+	return resources
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var commonMetadataYAML = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    app: app
+spec:
+  selector:
+    matchLabels:
+      app: app
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  schedule: "@daily"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            image: backup:1.0`
+
+func TestCommonMetadata(t *testing.T) {
+	r := strings.NewReader(commonMetadataYAML)
+	output, err := YAMLToTerraformResources(r, "", false, true, false,
+		WithLabels(map[string]string{"managed-by": "terraform", "team": "platform"}),
+		WithAnnotations(map[string]string{"owner": "platform@example.com"}))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	assert.Equal(t, 2, strings.Count(output, `"managed-by" = "terraform"`))
+	assert.Equal(t, 2, strings.Count(output, `"owner" = "platform@example.com"`))
+	assert.Contains(t, output, `"metadata" = {
+    "annotations" = {
+      "owner" = "platform@example.com"
+    }
+    "labels" = {
+      "app" = "app"
+      "managed-by" = "terraform"
+      "team" = "platform"
+    }
+    "name" = "app"
+  }`)
+}
+
+func TestCommonMetadataTemplatesAndSelectors(t *testing.T) {
+	r := strings.NewReader(commonMetadataYAML)
+	output, err := YAMLToTerraformResources(r, "", false, true, false,
+		WithLabels(map[string]string{"team": "platform"}),
+		WithSelectorLabels())
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `{
+  "apiVersion" = "apps/v1"
+  "kind" = "Deployment"
+  "metadata" = {
+    "labels" = {
+      "app" = "app"
+      "team" = "platform"
+    }
+    "name" = "app"
+  }
+  "spec" = {
+    "selector" = {
+      "matchLabels" = {
+        "app" = "app"
+        "team" = "platform"
+      }
+    }
+    "template" = {
+      "metadata" = {
+        "labels" = {
+          "app" = "app"
+          "team" = "platform"
+        }
+      }
+      "spec" = {
+        "containers" = [
+          {
+            "image" = "app:1.0"
+            "name" = "app"
+          },
+        ]
+      }
+    }
+  }
+}
+
+{
+  "apiVersion" = "batch/v1"
+  "kind" = "CronJob"
+  "metadata" = {
+    "labels" = {
+      "team" = "platform"
+    }
+    "name" = "backup"
+  }
+  "spec" = {
+    "jobTemplate" = {
+      "metadata" = {
+        "labels" = {
+          "team" = "platform"
+        }
+      }
+      "spec" = {
+        "template" = {
+          "metadata" = {
+            "labels" = {
+              "team" = "platform"
+            }
+          }
+          "spec" = {
+            "containers" = [
+              {
+                "image" = "backup:1.0"
+                "name" = "backup"
+              },
+            ]
+          }
+        }
+      }
+    }
+    "schedule" = "@daily"
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestParseKeyValues(t *testing.T) {
+	m, err := parseKeyValues([]string{"a=b", "c=d=e", "empty="})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"a": "b", "c": "d=e", "empty": ""}, m)
+	}
+
+	_, err = parseKeyValues([]string{"novalue"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// defaultProviderVersion is the first version of the kubernetes
+// provider where kubernetes_manifest is no longer experimental
+const defaultProviderVersion = ">= 2.7.0"
+
+// moduleNamespacesDir is the directory, relative to the module,
+// that the root module for each namespace is written to
+const moduleNamespacesDir = "namespaces"
+
+// variableReference matches a reference to an input variable
+var variableReference = regexp.MustCompile(`\bvar\.([A-Za-z_][A-Za-z0-9_-]*)`)
+
+// templateSequence matches an interpolation in a template string
+var templateSequence = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// namespaceName matches a valid Kubernetes namespace name
+var namespaceName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// collectVariables adds the input variables referenced by the
+// expressions in a value to vars
+func collectVariables(v cty.Value, vars map[string]bool, o *options) {
+	switch {
+	case v.IsNull() || !v.IsKnown():
+		return
+	case terraform.IsExpression(v):
+		for _, m := range variableReference.FindAllStringSubmatch(terraform.ExpressionString(v), -1) {
+			vars[m[1]] = true
+		}
+	case terraform.IsFunctionCall(v):
+		_, args := terraform.FunctionCallArgs(v)
+		for _, arg := range args {
+			collectVariables(arg, vars, o)
+		}
+	case v.Type() == cty.String:
+		if o.escaping != terraform.EscapeInterpolate {
+			return
+		}
+		for _, seq := range templateSequence.FindAllString(v.AsString(), -1) {
+			for _, m := range variableReference.FindAllStringSubmatch(seq, -1) {
+				vars[m[1]] = true
+			}
+		}
+	case v.CanIterateElements():
+		for it := v.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			collectVariables(ev, vars, o)
+		}
+	}
+}
+
+// moduleVariables returns the sorted names of the input variables
+// that the resources reference
+func moduleVariables(resources []resource, o *options) []string {
+	vars := map[string]bool{}
+	for _, r := range resources {
+		collectVariables(r.doc, vars, o)
+	}
+	names := []string{}
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// alignAttributes formats attributes with their equals signs lined up
+// the way terraform fmt does
+func alignAttributes(indent int, attrs [][2]string) string {
+	width := 0
+	for _, a := range attrs {
+		if len(a[0]) > width {
+			width = len(a[0])
+		}
+	}
+	var buf strings.Builder
+	for _, a := range attrs {
+		fmt.Fprintf(&buf, "%s%-*s = %s\n", strings.Repeat(" ", indent), width, a[0], a[1])
+	}
+	return buf.String()
+}
+
+// providersInModule returns true if the provider blocks are written
+// into the module itself rather than the root modules calling it
+func providersInModule(o *options) bool {
+	return len(o.providers) > 0 && len(o.moduleNamespaces) == 0
+}
+
+// formatVersions returns the contents of versions.tf
+func formatVersions(o *options) string {
+	version := o.providerVersion
+	if version == "" {
+		version = o.backend.version
+	}
+	attrs := [][2]string{
+		{"source", quote(o.backend.source)},
+		{"version", quote(version)},
+	}
+	if provider := resourceProvider(o); provider != "" && !providersInModule(o) {
+		attrs = append(attrs, [2]string{"configuration_aliases", fmt.Sprintf("[%s]", provider)})
+	}
+	return "terraform {\n" +
+		"  required_providers {\n" +
+		"    " + o.backend.provider + " = {\n" +
+		alignAttributes(6, attrs) +
+		"    }\n" +
+		"  }\n" +
+		"}\n"
+}
+
+// formatVariables returns a variable block for each of the names
+func formatVariables(names []string) string {
+	if len(names) == 0 {
+		return "# None of the values reference an input variable, use\n" +
+			"# --set-expr or --namespace-expr to replace them with one\n"
+	}
+	blocks := []string{}
+	for _, name := range names {
+		if name == "namespace" {
+			blocks = append(blocks, fmt.Sprintf("variable %q {\n", name)+
+				alignAttributes(2, [][2]string{
+					{"description", `"The namespace to create the objects in"`},
+					{"type", "string"},
+				})+
+				"}\n")
+			continue
+		}
+		blocks = append(blocks, fmt.Sprintf("variable %q {}\n", name))
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// formatOutputs returns the contents of outputs.tf
+func formatOutputs(resources []resource, o *options) string {
+	objects := [][2]string{}
+	for _, r := range resources {
+		objects = append(objects, [2]string{r.name, fmt.Sprintf("%s.%s.%s", o.backend.resourceType, r.name, o.backend.output)})
+	}
+	value := "{}"
+	if len(objects) > 0 {
+		value = "{\n" + alignAttributes(4, objects) + "  }"
+	}
+	// terraform fmt does not align attributes with multi-line values
+	return "output \"objects\" {\n" +
+		"  description = " + quote(o.backend.outputDescription) + "\n" +
+		"  value = " + value + "\n" +
+		"}\n"
+}
+
+// formatRootModule returns the main.tf of a root module that calls the
+// module with its namespace variable set, once for each of the namespaces
+// with for_each when the options ask for it
+func formatRootModule(name string, namespaces []string, variables []string, o *options) string {
+	hcl := ""
+	if len(o.providers) > 0 {
+		hcl += formatProviders(o) + "\n"
+	} else if alias := strings.TrimPrefix(o.providerAlias, o.backend.provider+"."); alias != o.providerAlias {
+		hcl += fmt.Sprintf("provider %q {\n  alias = %q\n}\n\n", o.backend.provider, alias)
+	}
+
+	hcl += fmt.Sprintf("module %q {\n", name)
+	namespace := ""
+	if o.moduleForEach {
+		hcl += alignAttributes(2, [][2]string{
+			{"for_each", namespaceSet(namespaces)},
+			{"source", quote("../..")},
+		})
+		namespace = "each.key"
+	} else {
+		hcl += fmt.Sprintf("  source = %q\n", "../..")
+		namespace = quote(namespaces[0])
+	}
+	if provider := resourceProvider(o); provider != "" {
+		hcl += fmt.Sprintf("\n  providers = {\n    %s = %s\n  }\n", provider, provider)
+	}
+
+	attrs := [][2]string{}
+	for _, v := range variables {
+		if v == "namespace" {
+			attrs = append(attrs, [2]string{v, namespace})
+		} else {
+			attrs = append(attrs, [2]string{v, "var." + v})
+		}
+	}
+	hcl += "\n" + alignAttributes(2, attrs)
+	hcl += "}\n"
+	return hcl
+}
+
+// namespaceSet returns the namespaces as a toset() expression
+// to use in for_each
+func namespaceSet(namespaces []string) string {
+	quoted := []string{}
+	for _, ns := range namespaces {
+		quoted = append(quoted, quote(ns))
+	}
+	return "toset([" + strings.Join(quoted, ", ") + "])"
+}
+
+// writeModule writes the resources to dir as a complete Terraform
+// module, along with a root module calling it for each of the
+// namespaces in the options
+func writeModule(dir string, resources []resource, o *options) error {
+	variables := moduleVariables(resources, o)
+	if len(o.moduleNamespaces) > 0 && !contains(variables, "namespace") {
+		return fmt.Errorf("writing a root module per namespace needs the objects to use var.namespace, e.g. --namespace-expr var.namespace")
+	}
+
+	hcl, err := formatResources(resources, o)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"main.tf":      hcl,
+		"versions.tf":  formatVersions(o),
+		"variables.tf": formatVariables(variables),
+		"outputs.tf":   formatOutputs(resources, o),
+	}
+	if providersInModule(o) {
+		files["providers.tf"] = formatProviders(o)
+	}
+	if o.imports && len(o.moduleNamespaces) == 0 {
+		// import blocks are only allowed in root modules
+		files["imports.tf"] = formatImports(resources, o, "", "")
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	name := snakify(filepath.Base(abs))
+	rootVariables := []string{}
+	for _, v := range variables {
+		if v != "namespace" {
+			rootVariables = append(rootVariables, v)
+		}
+	}
+	for _, ns := range o.moduleNamespaces {
+		if !namespaceName.MatchString(ns) {
+			return fmt.Errorf("%q is not a valid namespace name", ns)
+		}
+		if o.moduleForEach {
+			continue
+		}
+		root := filepath.Join(moduleNamespacesDir, ns)
+		files[filepath.Join(root, "main.tf")] = formatRootModule(name, []string{ns}, variables, o)
+		if len(rootVariables) > 0 {
+			files[filepath.Join(root, "variables.tf")] = formatVariables(rootVariables)
+		}
+		if o.imports {
+			files[filepath.Join(root, "imports.tf")] = formatImports(resources, o, "module."+name+".", ns)
+		}
+	}
+	if o.moduleForEach && len(o.moduleNamespaces) > 0 {
+		// a single root module calls the module for every namespace
+		root := moduleNamespacesDir
+		files[filepath.Join(root, "main.tf")] = formatRootModule(name, o.moduleNamespaces, variables, o)
+		if len(rootVariables) > 0 {
+			files[filepath.Join(root, "variables.tf")] = formatVariables(rootVariables)
+		}
+		if o.imports {
+			files[filepath.Join(root, "imports.tf")] = formatImportsForEach(resources, o, name, o.moduleNamespaces)
+		}
+	}
+
+	for filename, content := range files {
+		filename = filepath.Join(dir, filename)
+		err := os.MkdirAll(filepath.Dir(filename), 0755)
+		if err != nil {
+			return err
+		}
+		err = writeFile(filename, []byte(content))
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFiles(dir, resources)
+}
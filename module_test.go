@@ -0,0 +1,204 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteModule(t *testing.T) {
+	yaml := `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  KEY: value`
+
+	image, err := parseSetExpr("spec.template.spec.containers[name=app].image=var.image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := newOptions("", false, false, false, []Option{
+		WithSetExpressions(image),
+		WithNamespaceExpression("var.namespace"),
+		WithModuleNamespaces("dev", "prod"),
+	})
+	resources, err := convertManifests(strings.NewReader(yaml), o)
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "web")
+	err = writeModule(dir, resources, o)
+	if err != nil {
+		t.Fatal("Writing the module failed:", err)
+	}
+
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	hcl, err := formatResources(resources, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, hcl, read("main.tf"))
+
+	assert.Equal(t, `terraform {
+  required_providers {
+    kubernetes = {
+      source  = "hashicorp/kubernetes"
+      version = ">= 2.7.0"
+    }
+  }
+}
+`, read("versions.tf"))
+
+	assert.Equal(t, `variable "image" {}
+
+variable "namespace" {
+  description = "The namespace to create the objects in"
+  type        = string
+}
+`, read("variables.tf"))
+
+	assert.Equal(t, `output "objects" {
+  description = "The objects as they exist in the cluster, by resource name"
+  value = {
+    deployment_app   = kubernetes_manifest.deployment_app.object
+    configmap_config = kubernetes_manifest.configmap_config.object
+  }
+}
+`, read("outputs.tf"))
+
+	assert.Equal(t, `module "web" {
+  source = "../.."
+
+  image     = var.image
+  namespace = "prod"
+}
+`, read("namespaces/prod/main.tf"))
+	assert.Equal(t, "variable \"image\" {}\n", read("namespaces/prod/variables.tf"))
+
+	for _, name := range []string{"versions.tf", "variables.tf", "outputs.tf", "namespaces/dev/main.tf"} {
+		content := read(name)
+		assert.Equal(t, content, string(hclwrite.Format([]byte(content))), name+" is not formatted")
+	}
+}
+
+func TestWriteModuleProviderAlias(t *testing.T) {
+	o := newOptions("kubernetes.east", false, false, false, []Option{
+		WithNamespaceExpression("var.namespace"),
+		WithModuleNamespaces("dev"),
+	})
+
+	assert.Equal(t, `terraform {
+  required_providers {
+    kubernetes = {
+      source                = "hashicorp/kubernetes"
+      version               = ">= 2.7.0"
+      configuration_aliases = [kubernetes.east]
+    }
+  }
+}
+`, formatVersions(o))
+
+	assert.Equal(t, `provider "kubernetes" {
+  alias = "east"
+}
+
+module "web" {
+  source = "../.."
+
+  providers = {
+    kubernetes.east = kubernetes.east
+  }
+
+  namespace = "dev"
+}
+`, formatRootModule("web", []string{"dev"}, []string{"namespace"}, o))
+}
+
+func TestWriteModuleNamespacesWithoutVariable(t *testing.T) {
+	o := newOptions("", false, false, false, []Option{
+		WithNamespace("default"),
+		WithModuleNamespaces("dev"),
+	})
+	err := writeModule(t.TempDir(), []resource{}, o)
+	assert.Error(t, err)
+}
+
+func TestWriteModuleKubeconfigProviders(t *testing.T) {
+	providers, err := kubeconfigProviders("testdata/kubeconfig.yaml", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := newOptions("", false, false, false, []Option{WithKubeconfigProviders(providers...)})
+
+	dir := t.TempDir()
+	err = writeModule(dir, []resource{}, o)
+	if err != nil {
+		t.Fatal("Writing the module failed:", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "providers.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, formatProviders(newOptions("", false, false, false, []Option{WithKubeconfigProviders(providers...)})), string(b))
+	assert.NotContains(t, formatVersions(o), "configuration_aliases")
+}
+
+func TestWriteModuleForEach(t *testing.T) {
+	o := newOptions("", false, false, false, []Option{
+		WithNamespaceExpression("var.namespace"),
+		WithModuleNamespaces("dev", "prod"),
+		WithModuleForEach(),
+		WithImportBlocks(),
+	})
+	resources, err := convertManifests(strings.NewReader(importsYAML), o)
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "web")
+	err = writeModule(dir, resources[:1], o)
+	if err != nil {
+		t.Fatal("Writing the module failed:", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "namespaces", "main.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `module "web" {
+  for_each = toset(["dev", "prod"])
+  source   = "../.."
+
+  namespace = each.key
+}
+`, string(b))
+	assert.FileExists(t, filepath.Join(dir, "namespaces", "imports.tf"))
+	assert.NoDirExists(t, filepath.Join(dir, "namespaces", "dev"))
+}
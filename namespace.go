@@ -0,0 +1,142 @@
+package main
+
+import (
+	cty "github.com/zclconf/go-cty/cty"
+)
+
+// clusterScopedKinds is the list of built-in kinds that are not namespaced
+var clusterScopedKinds = []string{
+	"APIService",
+	"CertificateSigningRequest",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"ComponentStatus",
+	"CSIDriver",
+	"CSINode",
+	"CustomResourceDefinition",
+	"FlowSchema",
+	"IngressClass",
+	"MutatingWebhookConfiguration",
+	"Namespace",
+	"Node",
+	"PersistentVolume",
+	"PodSecurityPolicy",
+	"PriorityClass",
+	"PriorityLevelConfiguration",
+	"RuntimeClass",
+	"StorageClass",
+	"ValidatingAdmissionPolicy",
+	"ValidatingAdmissionPolicyBinding",
+	"ValidatingWebhookConfiguration",
+	"VolumeAttachment",
+}
+
+// subjectBindingKinds are the kinds whose subjects reference
+// ServiceAccounts by namespace
+var subjectBindingKinds = []string{
+	"RoleBinding",
+	"ClusterRoleBinding",
+}
+
+// contains returns true if the list contains s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterScoped returns the set of cluster-scoped kinds, including those
+// configured by the user and custom resources whose CRD is in the input
+func clusterScoped(resources []resource, o *options) map[string]bool {
+	kinds := map[string]bool{}
+	for _, k := range clusterScopedKinds {
+		kinds[k] = true
+	}
+	for _, k := range o.clusterScopedKinds {
+		kinds[k] = true
+	}
+	for _, r := range resources {
+		if getString(r.doc, "kind") != "CustomResourceDefinition" || getString(r.doc, "spec", "scope") != "Cluster" {
+			continue
+		}
+		if kind := getString(r.doc, "spec", "names", "kind"); kind != "" {
+			kinds[kind] = true
+		}
+	}
+	return kinds
+}
+
+// documentNamespace returns the namespace of a document,
+// or "default" if it doesn't have one
+func documentNamespace(doc cty.Value) string {
+	if ns := getString(doc, "metadata", "namespace"); ns != "" {
+		return ns
+	}
+	return "default"
+}
+
+// overrideNamespace sets metadata.namespace on every namespaced document
+// and points ServiceAccount subjects of role bindings that referred to
+// one of the original namespaces at the new one
+func overrideNamespace(resources []resource, o *options) []resource {
+	kinds := clusterScoped(resources, o)
+
+	original := map[string]bool{}
+	for _, r := range resources {
+		if !kinds[getString(r.doc, "kind")] {
+			original[documentNamespace(r.doc)] = true
+		}
+	}
+
+	for i, r := range resources {
+		m := r.doc.AsValueMap()
+		kind := getString(r.doc, "kind")
+
+		if !kinds[kind] {
+			metadata := valueMap(m["metadata"])
+			metadata["namespace"] = o.namespace
+			m["metadata"] = cty.ObjectVal(metadata)
+		}
+
+		if contains(subjectBindingKinds, kind) {
+			if v, ok := m["subjects"]; ok && v.Type().IsTupleType() {
+				m["subjects"] = overrideSubjectNamespaces(v, original, o.namespace)
+			}
+		}
+
+		resources[i].doc = cty.ObjectVal(m)
+	}
+
+	return resources
+}
+
+// overrideSubjectNamespaces rewrites the namespace of ServiceAccount subjects
+// that have no namespace or whose namespace is in the original set
+func overrideSubjectNamespaces(subjects cty.Value, original map[string]bool, namespace cty.Value) cty.Value {
+	if subjects.LengthInt() == 0 {
+		return subjects
+	}
+
+	rewritten := []cty.Value{}
+	for _, s := range subjects.AsValueSlice() {
+		if !s.Type().IsObjectType() {
+			rewritten = append(rewritten, s)
+			continue
+		}
+		if getString(s, "kind") != "ServiceAccount" {
+			rewritten = append(rewritten, s)
+			continue
+		}
+		if ns := getString(s, "namespace"); ns != "" && !original[ns] {
+			rewritten = append(rewritten, s)
+			continue
+		}
+		subject := valueMap(s)
+		subject["namespace"] = namespace
+		rewritten = append(rewritten, cty.ObjectVal(subject))
+	}
+	return cty.TupleVal(rewritten)
+}
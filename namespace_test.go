@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var namespaceBundleYAML = `---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: app
+  namespace: staging
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: app
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: staging
+- kind: ServiceAccount
+  name: monitoring
+  namespace: kube-system
+- kind: Group
+  name: developers`
+
+func TestNamespaceOverride(t *testing.T) {
+	r := strings.NewReader(namespaceBundleYAML)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithNamespace("prod"))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubernetes_manifest" "serviceaccount_prod_app" {
+  manifest = {
+    "apiVersion" = "v1"
+    "kind" = "ServiceAccount"
+    "metadata" = {
+      "name" = "app"
+      "namespace" = "prod"
+    }
+  }
+}
+
+resource "kubernetes_manifest" "clusterrole_app" {
+  manifest = {
+    "apiVersion" = "rbac.authorization.k8s.io/v1"
+    "kind" = "ClusterRole"
+    "metadata" = {
+      "name" = "app"
+    }
+  }
+}
+
+resource "kubernetes_manifest" "rolebinding_prod_app" {
+  manifest = {
+    "apiVersion" = "rbac.authorization.k8s.io/v1"
+    "kind" = "RoleBinding"
+    "metadata" = {
+      "name" = "app"
+      "namespace" = "prod"
+    }
+    "roleRef" = {
+      "apiGroup" = "rbac.authorization.k8s.io"
+      "kind" = "ClusterRole"
+      "name" = "app"
+    }
+    "subjects" = [
+      {
+        "kind" = "ServiceAccount"
+        "name" = "app"
+        "namespace" = "prod"
+      },
+      {
+        "kind" = "ServiceAccount"
+        "name" = "monitoring"
+        "namespace" = "kube-system"
+      },
+      {
+        "kind" = "Group"
+        "name" = "developers"
+      },
+    ]
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestNamespaceOverrideNullSubjectFields(t *testing.T) {
+	r := strings.NewReader(`---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: app
+  namespace: default
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: app
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: null
+- kind: null
+  name: developers
+`)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithNamespace("prod"))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	// a null namespace is the same as a missing one
+	assert.Contains(t, output, `{
+        "kind" = "ServiceAccount"
+        "name" = "app"
+        "namespace" = "prod"
+      },
+      {
+        "kind" = null
+        "name" = "developers"
+      },`)
+}
+
+func TestNamespaceExpression(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: default
+data:
+  TEST: test`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", true, false, false,
+		WithNamespaceExpression("var.namespace"))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubernetes_manifest" "configmap_test" {
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      "TEST" = "test"
+    }
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+      "namespace" = var.namespace
+    }
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestNamespaceClusterScopedCustomResources(t *testing.T) {
+	yaml := `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: clusterissuers.cert-manager.io
+spec:
+  group: cert-manager.io
+  scope: Cluster
+  names:
+    kind: ClusterIssuer
+---
+apiVersion: cert-manager.io/v1
+kind: ClusterIssuer
+metadata:
+  name: letsencrypt
+---
+apiVersion: example.com/v1
+kind: Tenant
+metadata:
+  name: example
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: web`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, true, false,
+		WithNamespace("prod"), WithClusterScopedKinds("Tenant"))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	assert.Equal(t, 1, strings.Count(output, `"namespace" = "prod"`))
+	assert.Contains(t, output, `"kind" = "Certificate"
+  "metadata" = {
+    "name" = "web"
+    "namespace" = "prod"
+  }`)
+}
+
+func TestNamespaceOverrideCollision(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+  namespace: x`
+
+	r := strings.NewReader(yaml)
+	_, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithNamespace("prod"))
+	assert.EqualError(t, err, `documents v1/ConfigMap/prod/a and v1/ConfigMap/prod/a would both be written as resource "configmap_prod_a", use --duplicates to resolve this`)
+
+	var warnings bytes.Buffer
+	r = strings.NewReader(yaml)
+	_, err = YAMLToTerraformResources(r, "", false, false, false,
+		WithNamespaceExpression("var.namespace"), WithWarnings(&warnings))
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+	assert.Equal(t, "warning: document v1/ConfigMap/var.namespace/a appears more than once after overriding the namespace\n", warnings.String())
+}
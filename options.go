@@ -0,0 +1,412 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// options holds the settings for a single conversion
+type options struct {
+	providerAlias   string
+	stripServerSide bool
+	mapOnly         bool
+	stripKeyQuotes  bool
+
+	// skipGenerated drops objects owned by controllers and
+	// objects the cluster creates itself
+	skipGenerated bool
+
+	// crdStageWebhooks puts webhook configurations into
+	// the CRD stage of --crd-stage
+	crdStageWebhooks bool
+
+	// duplicates is how clashing resource names are resolved
+	duplicates duplicateStrategy
+
+	// namespace is set on every namespaced object when it is not cty.NilVal
+	namespace cty.Value
+
+	// clusterScopedKinds are kinds, in addition to the built-in ones,
+	// that never get a namespace
+	clusterScopedKinds []string
+
+	// setExprs replace values in every document with Terraform expressions
+	setExprs []setExpr
+
+	// labels and annotations are added to the metadata of every document
+	labels      map[string]string
+	annotations map[string]string
+
+	// templateMetadata adds the labels and annotations to pod templates
+	// and the job templates of CronJobs as well
+	templateMetadata bool
+
+	// selectorLabels adds the labels to the selectors of workloads,
+	// which implies templateMetadata so the selectors still match
+	selectorLabels bool
+
+	// externalizeThreshold is the size in bytes at which ConfigMap
+	// and Secret values are moved into their own files, 0 disables it
+	externalizeThreshold int
+
+	// decodeSecrets writes readable Secret data as base64encode() calls
+	decodeSecrets bool
+
+	// encodeStructured writes JSON and YAML documents held in ConfigMaps
+	// and Secrets as jsonencode() and yamlencode() calls
+	encodeStructured bool
+
+	// escaping is how template sequences in strings are escaped
+	escaping terraform.Escaping
+
+	// heredoc is when multi-line strings are written as heredocs
+	heredoc terraform.Heredoc
+
+	// strict parses YAML with the YAML 1.2 core schema
+	strict bool
+
+	// providerVersion is the version constraint for the provider
+	// written to versions.tf by --module-dir, empty for the
+	// default of the backend
+	providerVersion string
+
+	// moduleNamespaces are the namespaces to write a root module for,
+	// each calling the module with its namespace variable set
+	moduleNamespaces []string
+
+	// moduleForEach writes a single root module that calls the module
+	// for each of moduleNamespaces with for_each
+	moduleForEach bool
+
+	// providers are written before the resources, and the resources
+	// use the first of them
+	providers []kubernetesProvider
+
+	// target is the tool and version the output has to work with
+	target target
+
+	// imports writes an import block for every resource
+	imports bool
+
+	// prune writes removed blocks for the resources that --update
+	// finds in the configuration but not in the manifests
+	prune bool
+
+	// manifestDecode writes the manifests of kubernetes_manifest
+	// resources as YAML passed to a function that decodes it
+	manifestDecode bool
+
+	// backend is the resource type the manifests are written as
+	backend backend
+
+	// language is the CDK for Terraform language to write,
+	// nil writes HCL
+	language *language
+
+	// verify parses the output and checks it reads back as the input
+	verify bool
+
+	// includeKinds and excludeKinds filter the documents by kind,
+	// namespaceFilter by namespace and selector by labels
+	includeKinds    []string
+	excludeKinds    []string
+	namespaceFilter []string
+	selector        selector
+
+	// warnings is where non-fatal problems with the input are reported
+	warnings io.Writer
+}
+
+// Option configures a conversion done by YAMLToTerraformResources
+type Option func(*options)
+
+// WithDuplicateStrategy sets how resources that would end up with
+// the same Terraform resource name are handled
+func WithDuplicateStrategy(s duplicateStrategy) Option {
+	return func(o *options) {
+		o.duplicates = s
+	}
+}
+
+// WithNamespace sets metadata.namespace on every namespaced object
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = cty.StringVal(namespace)
+	}
+}
+
+// WithNamespaceExpression sets metadata.namespace on every namespaced
+// object to a Terraform expression, such as var.namespace
+func WithNamespaceExpression(expr string) Option {
+	return func(o *options) {
+		o.namespace = terraform.ExpressionVal(expr)
+	}
+}
+
+// WithClusterScopedKinds adds kinds that should be treated as cluster-scoped
+// when overriding the namespace, such as custom resources defined outside of the input
+func WithClusterScopedKinds(kinds ...string) Option {
+	return func(o *options) {
+		o.clusterScopedKinds = append(o.clusterScopedKinds, kinds...)
+	}
+}
+
+// WithSetExpressions replaces the values matched by each path with a raw
+// Terraform expression, see parseSetExpr for the syntax
+func WithSetExpressions(exprs ...setExpr) Option {
+	return func(o *options) {
+		o.setExprs = append(o.setExprs, exprs...)
+	}
+}
+
+// WithLabels adds labels to every document
+func WithLabels(labels map[string]string) Option {
+	return func(o *options) {
+		o.labels = labels
+	}
+}
+
+// WithAnnotations adds annotations to every document
+func WithAnnotations(annotations map[string]string) Option {
+	return func(o *options) {
+		o.annotations = annotations
+	}
+}
+
+// WithTemplateMetadata adds the labels and annotations to pod templates
+// and CronJob job templates too
+func WithTemplateMetadata() Option {
+	return func(o *options) {
+		o.templateMetadata = true
+	}
+}
+
+// WithSelectorLabels adds the labels to the selectors of workloads
+// and to the pod templates they select
+func WithSelectorLabels() Option {
+	return func(o *options) {
+		o.selectorLabels = true
+	}
+}
+
+// WithExternalizeThreshold moves ConfigMap and Secret values of at least
+// threshold bytes into files that are read with file() or filebase64().
+// The contents of the files are returned on the resources from convertManifests.
+func WithExternalizeThreshold(threshold int) Option {
+	return func(o *options) {
+		o.externalizeThreshold = threshold
+	}
+}
+
+// WithDecodedSecrets writes Secret data that decodes to readable text
+// as a base64encode() call on the text instead of the base64 string
+func WithDecodedSecrets() Option {
+	return func(o *options) {
+		o.decodeSecrets = true
+	}
+}
+
+// WithStructuredData writes ConfigMap data and Secret stringData values that
+// hold JSON or YAML documents as jsonencode() or yamlencode() calls
+func WithStructuredData() Option {
+	return func(o *options) {
+		o.encodeStructured = true
+	}
+}
+
+// WithEscaping sets how the template sequences ${ and %{ are escaped
+func WithEscaping(e terraform.Escaping) Option {
+	return func(o *options) {
+		o.escaping = e
+	}
+}
+
+// WithHeredoc sets when multi-line strings are written as heredocs
+func WithHeredoc(h terraform.Heredoc) Option {
+	return func(o *options) {
+		o.heredoc = h
+	}
+}
+
+// WithStrictYAML parses the input using the YAML 1.2 core schema,
+// rejecting duplicate keys and keeping numbers exactly as written
+func WithStrictYAML() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithProviderVersion sets the version constraint for the kubernetes
+// provider in the versions.tf of a module
+func WithProviderVersion(version string) Option {
+	return func(o *options) {
+		o.providerVersion = version
+	}
+}
+
+// WithModuleNamespaces writes a root module for each namespace that calls
+// the module with its namespace variable set to that namespace
+func WithModuleNamespaces(namespaces ...string) Option {
+	return func(o *options) {
+		o.moduleNamespaces = append(o.moduleNamespaces, namespaces...)
+	}
+}
+
+// WithModuleForEach writes a single root module that calls the module for
+// each of the module namespaces with for_each, instead of a root module per
+// namespace
+func WithModuleForEach() Option {
+	return func(o *options) {
+		o.moduleForEach = true
+	}
+}
+
+// WithKubeconfigProviders writes a provider block for each of the providers
+// and sets the provider of every resource to the first of them
+func WithKubeconfigProviders(providers ...kubernetesProvider) Option {
+	return func(o *options) {
+		o.providers = append(o.providers, providers...)
+	}
+}
+
+// WithTarget sets the tool and version the output has to work with,
+// which decides the language features that can be used
+func WithTarget(t target) Option {
+	return func(o *options) {
+		o.target = t
+	}
+}
+
+// WithImportBlocks writes an import block for every resource so that
+// existing objects are adopted, or an import command in a comment when
+// the target does not support import blocks
+func WithImportBlocks() Option {
+	return func(o *options) {
+		o.imports = true
+	}
+}
+
+// WithPrune replaces the resources that are no longer in the manifests
+// with removed blocks when updating a configuration, so that Terraform
+// stops managing the objects without deleting them
+func WithPrune() Option {
+	return func(o *options) {
+		o.prune = true
+	}
+}
+
+// WithManifestDecode writes the manifests of kubernetes_manifest resources
+// as YAML decoded by provider::kubernetes::manifest_decode, or by yamldecode
+// when the target does not support provider-defined functions
+func WithManifestDecode() Option {
+	return func(o *options) {
+		o.manifestDecode = true
+	}
+}
+
+// WithBackend sets the resource type the manifests are written as
+func WithBackend(b backend) Option {
+	return func(o *options) {
+		o.backend = b
+	}
+}
+
+// WithLanguage writes the resources as CDK for Terraform code in
+// the language instead of HCL
+func WithLanguage(l language) Option {
+	return func(o *options) {
+		o.language = &l
+	}
+}
+
+// WithVerify parses the HCL that is written and returns an error
+// if any manifest does not read back as the document it came from
+func WithVerify() Option {
+	return func(o *options) {
+		o.verify = true
+	}
+}
+
+// WithSkipGenerated skips objects owned by a controller, such as the Pods
+// of a ReplicaSet, and well-known objects the cluster creates
+func WithSkipGenerated() Option {
+	return func(o *options) {
+		o.skipGenerated = true
+	}
+}
+
+// WithCRDStageWebhooks puts MutatingWebhookConfigurations and
+// ValidatingWebhookConfigurations into the CRD stage with the CRDs
+func WithCRDStageWebhooks() Option {
+	return func(o *options) {
+		o.crdStageWebhooks = true
+	}
+}
+
+// WithKindFilter only converts documents of the included kinds, if
+// any, and skips documents of the excluded kinds, ignoring case
+func WithKindFilter(include, exclude []string) Option {
+	return func(o *options) {
+		o.includeKinds = append(o.includeKinds, include...)
+		o.excludeKinds = append(o.excludeKinds, exclude...)
+	}
+}
+
+// WithNamespaceFilter only converts documents in these namespaces
+func WithNamespaceFilter(namespaces ...string) Option {
+	return func(o *options) {
+		o.namespaceFilter = append(o.namespaceFilter, namespaces...)
+	}
+}
+
+// WithSelector only converts documents whose labels match the
+// selector, see parseSelector for the syntax
+func WithSelector(sel selector) Option {
+	return func(o *options) {
+		o.selector = sel
+	}
+}
+
+// WithWarnings sets the writer that warnings about the input are written to
+func WithWarnings(w io.Writer) Option {
+	return func(o *options) {
+		o.warnings = w
+	}
+}
+
+func newOptions(
+	providerAlias string, stripServerSide bool,
+	mapOnly bool, stripKeyQuotes bool, opts []Option) *options {
+	o := &options{
+		providerAlias:   providerAlias,
+		stripServerSide: stripServerSide,
+		mapOnly:         mapOnly,
+		stripKeyQuotes:  stripKeyQuotes,
+		duplicates:      duplicateError,
+		backend:         backendManifest,
+		target:          defaultTarget,
+		warnings:        io.Discard,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// formatOptions returns the options for formatting values as HCL
+func (o *options) formatOptions() terraform.FormatOptions {
+	return terraform.FormatOptions{
+		StripKeyQuotes: o.stripKeyQuotes,
+		Escaping:       o.escaping,
+		Heredoc:        o.heredoc,
+	}
+}
+
+// warnf writes a warning message
+func (o *options) warnf(format string, a ...interface{}) {
+	fmt.Fprintf(o.warnings, "warning: "+format+"\n", a...)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	cty "github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	yaml12 "gopkg.in/yaml.v3"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// terraformToYAML is the reverse of YAMLToTerraformResources. It reads
+// the manifests of the resources of every backend in a configuration, or
+// a single map when mapOnly is set, and writes them as YAML documents.
+func terraformToYAML(r io.Reader, mapOnly bool) (string, error) {
+	buf := bytes.Buffer{}
+	if _, err := buf.ReadFrom(r); err != nil {
+		return "", err
+	}
+	src := buf.Bytes()
+
+	manifests := []cty.Value{}
+	if mapOnly {
+		expr, diags := hclsyntax.ParseExpression(src, "manifest", hcl.InitialPos)
+		if diags.HasErrors() {
+			return "", fmt.Errorf("%s", diags.Error())
+		}
+		manifests = append(manifests, configValue(expr, src))
+	} else {
+		f, diags := hclsyntax.ParseConfig(src, "main.tf", hcl.InitialPos)
+		if diags.HasErrors() {
+			return "", fmt.Errorf("%s", diags.Error())
+		}
+		for _, b := range f.Body.(*hclsyntax.Body).Blocks {
+			if b.Type != "resource" || len(b.Labels) != 2 {
+				continue
+			}
+			for _, be := range backends {
+				if b.Labels[0] != be.resourceType {
+					continue
+				}
+				address := fmt.Sprintf("%s.%s", b.Labels[0], b.Labels[1])
+				attr, ok := b.Body.Attributes[be.attribute]
+				if !ok {
+					return "", fmt.Errorf("%s has no %s", address, be.attribute)
+				}
+				doc := configValue(attr.Expr, src)
+				if be.yaml || terraform.IsFunctionCall(doc) {
+					var err error
+					if doc, err = yamlConfigValue(doc); err != nil {
+						return "", fmt.Errorf("%s: %s", address, err)
+					}
+				}
+				manifests = append(manifests, doc)
+			}
+		}
+		if len(manifests) == 0 {
+			return "", fmt.Errorf("there are no resources with manifests in the configuration")
+		}
+	}
+
+	docs := []string{}
+	for _, m := range manifests {
+		doc, err := literalValue(m, nil)
+		if err != nil {
+			return "", err
+		}
+		node, ok := yamlNode(doc)
+		if !ok {
+			return "", fmt.Errorf("can't write a value of type %s as YAML", doc.Type().FriendlyName())
+		}
+		out := bytes.Buffer{}
+		enc := yaml12.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(node); err != nil {
+			return "", err
+		}
+		if err := enc.Close(); err != nil {
+			return "", err
+		}
+		docs = append(docs, out.String())
+	}
+	return "---\n" + strings.Join(docs, "---\n"), nil
+}
+
+// literalValue evaluates the functions tfk8s writes, such as jsonencode(),
+// so that the value can be written as YAML. Other Terraform expressions
+// can only be evaluated by Terraform, so they are an error.
+func literalValue(v cty.Value, path []pathStep) (cty.Value, error) {
+	ty := v.Type()
+	switch {
+	case terraform.IsExpression(v):
+		return cty.NilVal, fmt.Errorf("%s: the Terraform expression %s can't be written as YAML",
+			verifyPath(path), terraform.ExpressionString(v))
+	case terraform.IsFunctionCall(v):
+		name, args := terraform.FunctionCallArgs(v)
+		if len(args) != 1 {
+			return cty.NilVal, fmt.Errorf("%s: the call to %s() can't be written as YAML", verifyPath(path), name)
+		}
+		arg, err := literalValue(args[0], path)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		switch name {
+		case "jsonencode":
+			b, err := ctyjson.Marshal(arg, arg.Type())
+			if err != nil {
+				return cty.NilVal, fmt.Errorf("%s: %s", verifyPath(path), err)
+			}
+			return cty.StringVal(string(b)), nil
+		case "yamlencode":
+			// Terraform's yamlencode, which quotes every key and string
+			b, err := ctyyaml.Standard.Marshal(arg)
+			if err != nil {
+				return cty.NilVal, fmt.Errorf("%s: %s", verifyPath(path), err)
+			}
+			return cty.StringVal(string(b)), nil
+		case "base64encode":
+			if arg.Type() == cty.String && !arg.IsNull() {
+				return cty.StringVal(base64.StdEncoding.EncodeToString([]byte(arg.AsString()))), nil
+			}
+		}
+		return cty.NilVal, fmt.Errorf("%s: the call to %s() can't be written as YAML", verifyPath(path), name)
+	case v.IsNull():
+		return v, nil
+	case ty.IsObjectType() || ty.IsMapType():
+		m := valueMap(v)
+		for k, ev := range m {
+			lv, err := literalValue(ev, append(path[:len(path):len(path)], pathStep{attr: k}))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			m[k] = lv
+		}
+		return cty.ObjectVal(m), nil
+	case ty.IsTupleType() || ty.IsListType():
+		elems := v.AsValueSlice()
+		for i, ev := range elems {
+			lv, err := literalValue(ev, append(path[:len(path):len(path)], pathStep{index: i}))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems[i] = lv
+		}
+		return cty.TupleVal(elems), nil
+	}
+	return v, nil
+}
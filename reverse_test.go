@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+const reverseYAML = `---
+apiVersion: v1
+data:
+  config.json: '{"a":[1,2],"b":"${HOME}"}'
+  mode: "0644"
+  script: |
+    echo ${HOME}
+  settings.yaml: |
+    "a":
+      "b":
+      - 1
+kind: ConfigMap
+metadata:
+  name: test
+---
+apiVersion: v1
+data:
+  key: aGVsbG8=
+kind: Secret
+metadata:
+  name: test
+`
+
+func TestTerraformToYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "manifest"},
+		{name: "function calls", opts: []Option{WithStructuredData(), WithDecodedSecrets(), WithHeredoc(terraform.HeredocAlways)}},
+		{name: "manifest decode", opts: []Option{WithManifestDecode(), WithTarget(target{toolTerraform, version{1, 8}})}},
+		{name: "kubectl", opts: []Option{WithBackend(backendKubectl)}},
+		{name: "k8s", opts: []Option{WithBackend(backendK8s)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hcl, err := YAMLToTerraformResources(strings.NewReader(reverseYAML), "", false, false, true, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output, err := terraformToYAML(strings.NewReader(hcl), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, reverseYAML, output)
+		})
+	}
+}
+
+func TestTerraformToYAMLErrors(t *testing.T) {
+	_, err := terraformToYAML(strings.NewReader(`resource "random_id" "test" {}`), false)
+	if assert.Error(t, err) {
+		assert.Equal(t, "there are no resources with manifests in the configuration", err.Error())
+	}
+
+	_, err = terraformToYAML(strings.NewReader(`{ "data" = { "a" = file("a.txt") } }`), true)
+	if assert.Error(t, err) {
+		assert.Equal(t, "data.a: the call to file() can't be written as YAML", err.Error())
+	}
+}
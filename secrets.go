@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/base64"
+	"unicode/utf8"
+
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// readable returns true if the bytes are text that can be
+// reviewed, that is UTF-8 without control characters
+func readable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\n' && r != '\t' {
+			return false
+		}
+		if r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeSecretValue returns a base64encode() call on the decoded value
+// when it is readable text, wrapping a jsonencode() or yamlencode() call
+// when the text is a JSON or YAML document
+func decodeSecretValue(v cty.Value) (cty.Value, bool) {
+	if v.Type() != cty.String || v.IsNull() {
+		return v, false
+	}
+	b, err := base64.StdEncoding.DecodeString(v.AsString())
+	if err != nil || !readable(b) {
+		return v, false
+	}
+	if structured, ok := structuredValue(string(b)); ok {
+		return terraform.FunctionCallVal("base64encode", structured), true
+	}
+	return terraform.FunctionCallVal("base64encode", cty.StringVal(string(b))), true
+}
+
+// decodeSecrets replaces the base64 encoded data of Secrets
+// with the decoded value wherever it is readable
+func decodeSecrets(resources []resource) []resource {
+	for i, r := range resources {
+		if getString(r.doc, "kind") != "Secret" {
+			continue
+		}
+		resources[i].doc = updateAttr(r.doc, false, func(data cty.Value) cty.Value {
+			if data == cty.NilVal || data.IsNull() || !data.Type().IsObjectType() {
+				return data
+			}
+			m := valueMap(data)
+			for k, v := range m {
+				if decoded, ok := decodeSecretValue(v); ok {
+					m[k] = decoded
+				}
+			}
+			return cty.ObjectVal(m)
+		}, "data")
+	}
+	return resources
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSecrets(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+type: Opaque
+data:
+  password: aHVudGVyMg==
+  .dockerconfigjson: eyJhdXRocyI6eyJyZWdpc3RyeS5leGFtcGxlLmNvbSI6eyJhdXRoIjoiZFhObGNqcHdZWE56In19fQ==
+  users.yaml: dXNlcjogYWRtaW4Kcm9sZXM6Ci0gcmVhZAotIHdyaXRlCg==
+  binary: AAEC/w==`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithDecodedSecrets())
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubernetes_manifest" "secret_test" {
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      ".dockerconfigjson" = base64encode(jsonencode({
+        "auths" = {
+          "registry.example.com" = {
+            "auth" = "dXNlcjpwYXNz"
+          }
+        }
+      }))
+      "binary" = "AAEC/w=="
+      "password" = base64encode("hunter2")
+      "users.yaml" = base64encode(<<-EOT
+      user: admin
+      roles:
+      - read
+      - write
+      EOT
+      )
+    }
+    "kind" = "Secret"
+    "metadata" = {
+      "name" = "test"
+    }
+    "type" = "Opaque"
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestDecodeSecretsExternalized(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+data:
+  users.yaml: dXNlcjogYWRtaW4Kcm9sZXM6Ci0gcmVhZAotIHdyaXRlCg==`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithDecodedSecrets(), WithExternalizeThreshold(8))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	assert.Contains(t, output,
+		`"users.yaml" = base64encode(file("${path.module}/files/secret_test/users.yaml"))`)
+}
+
+func TestDecodeSecretsWithoutData(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+type: Opaque`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithDecodedSecrets())
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubernetes_manifest" "secret_test" {
+  manifest = {
+    "apiVersion" = "v1"
+    "kind" = "Secret"
+    "metadata" = {
+      "name" = "test"
+    }
+    "type" = "Opaque"
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
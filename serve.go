@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+// defaultMaxRequestBytes is the largest request body tfk8s serve accepts by default
+const defaultMaxRequestBytes = 10 << 20
+
+// newServer returns the handler for tfk8s serve. POST /convert turns YAML
+// or JSON manifests into HCL and POST /reverse turns HCL back into YAML,
+// with the options in the query string. GET /healthz reports that the
+// server is up.
+func newServer(maxRequestBytes int64) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			methodNotAllowed(w, "GET, HEAD")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, "ok\n")
+	})
+	mux.HandleFunc("/convert", func(w http.ResponseWriter, req *http.Request) {
+		serveConversion(w, req, maxRequestBytes, "text/plain; charset=utf-8", convertRequest)
+	})
+	mux.HandleFunc("/reverse", func(w http.ResponseWriter, req *http.Request) {
+		serveConversion(w, req, maxRequestBytes, "application/yaml", reverseRequest)
+	})
+	return mux
+}
+
+// convertRequest converts the manifests in a request to HCL. The query
+// options have the same names as the command line flags.
+func convertRequest(body io.Reader, req *http.Request) (string, error) {
+	strip, err := queryBool(req, "strip")
+	if err != nil {
+		return "", err
+	}
+	mapOnly, err := queryBool(req, "map-only")
+	if err != nil {
+		return "", err
+	}
+	stripKeyQuotes, err := queryBool(req, "strip-key-quotes")
+	if err != nil {
+		return "", err
+	}
+	provider := req.URL.Query().Get("provider")
+	return YAMLToTerraformResources(body, provider, strip, mapOnly, stripKeyQuotes)
+}
+
+// reverseRequest converts the HCL in a request to YAML
+func reverseRequest(body io.Reader, req *http.Request) (string, error) {
+	mapOnly, err := queryBool(req, "map-only")
+	if err != nil {
+		return "", err
+	}
+	return terraformToYAML(body, mapOnly)
+}
+
+// serveConversion runs a conversion on the body of a POST request, limited
+// to maxRequestBytes, and writes the result or the error
+func serveConversion(w http.ResponseWriter, req *http.Request, maxRequestBytes int64, contentType string,
+	convert func(io.Reader, *http.Request) (string, error)) {
+	if req.Method != http.MethodPost {
+		methodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxRequestBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("error: the request body is larger than %d bytes", maxRequestBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	out, err := convert(bytes.NewReader(body), req)
+	if err != nil {
+		http.Error(w, "error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	fmt.Fprint(w, out)
+}
+
+// methodNotAllowed rejects a request with a method the endpoint doesn't support
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, "error: method not allowed", http.StatusMethodNotAllowed)
+}
+
+// queryBool parses a boolean query option, which is false if it is missing
+func queryBool(req *http.Request, name string) (bool, error) {
+	s := req.URL.Query().Get(name)
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("the %s option must be true or false", name)
+	}
+	return b, nil
+}
+
+// serve runs tfk8s serve with the arguments after the subcommand
+func serve(args []string) {
+	flags := flag.NewFlagSet("tfk8s serve", flag.ExitOnError)
+	listen := flags.String("listen", ":8080", "Address to listen on")
+	maxRequestBytes := flags.Int64("max-request-bytes", defaultMaxRequestBytes, "Largest request body to accept")
+	flags.Parse(args)
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           newServer(*maxRequestBytes),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "listening on %s\r\n", *listen)
+	if err := server.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// request sends a request to a server with the given request size limit
+func request(maxRequestBytes int64, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	newServer(maxRequestBytes).ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func TestServeConvert(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  resourceVersion: "1"
+data:
+  TEST: test`
+
+	w := request(defaultMaxRequestBytes, http.MethodPost, "/convert?strip=true&strip-key-quotes=true&provider=kubernetes.east", yaml)
+	expected, err := YAMLToTerraformResources(strings.NewReader(yaml), "kubernetes.east", true, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, expected, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "resourceVersion")
+}
+
+func TestServeConvertJSON(t *testing.T) {
+	json := `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "test"}}`
+
+	w := request(defaultMaxRequestBytes, http.MethodPost, "/convert?map-only=1", json)
+
+	expected := `{
+  "apiVersion" = "v1"
+  "kind" = "Namespace"
+  "metadata" = {
+    "name" = "test"
+  }
+}
+`
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, expected, w.Body.String())
+}
+
+func TestServeReverse(t *testing.T) {
+	hcl := `
+resource "kubernetes_manifest" "configmap_test" {
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      "TEST" = "test"
+    }
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+    }
+  }
+}`
+
+	w := request(defaultMaxRequestBytes, http.MethodPost, "/reverse", hcl)
+
+	expected := `---
+apiVersion: v1
+data:
+  TEST: test
+kind: ConfigMap
+metadata:
+  name: test
+`
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+	assert.Equal(t, expected, w.Body.String())
+}
+
+func TestServeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		code     int
+		expected string
+	}{
+		{
+			name:     "invalid YAML",
+			method:   http.MethodPost,
+			target:   "/convert",
+			body:     "kind: [",
+			code:     http.StatusBadRequest,
+			expected: "error: yaml: line 1: did not find expected node content\n",
+		},
+		{
+			name:     "invalid option",
+			method:   http.MethodPost,
+			target:   "/convert?strip=maybe",
+			body:     "kind: Namespace",
+			code:     http.StatusBadRequest,
+			expected: "error: the strip option must be true or false\n",
+		},
+		{
+			name:     "too large",
+			method:   http.MethodPost,
+			target:   "/convert",
+			body:     strings.Repeat("#", 65),
+			code:     http.StatusRequestEntityTooLarge,
+			expected: "error: the request body is larger than 64 bytes\n",
+		},
+		{
+			name:     "expression",
+			method:   http.MethodPost,
+			target:   "/reverse?map-only=true",
+			body:     `{ "metadata" = { "namespace" = var.namespace } }`,
+			code:     http.StatusBadRequest,
+			expected: "error: metadata.namespace: the Terraform expression var.namespace can't be written as YAML\n",
+		},
+		{
+			name:     "method",
+			method:   http.MethodGet,
+			target:   "/convert",
+			code:     http.StatusMethodNotAllowed,
+			expected: "error: method not allowed\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(64, tt.method, tt.target, tt.body)
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, tt.expected, w.Body.String())
+		})
+	}
+}
+
+func TestServeHealth(t *testing.T) {
+	w := request(defaultMaxRequestBytes, http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+}
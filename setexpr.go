@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// pathStep is a single step in a path such as
+// spec.template.spec.containers[name=app].image
+type pathStep struct {
+	// attr is the attribute to select from an object
+	attr string
+
+	// index is the element to select from a list when attr and key are empty
+	index int
+
+	// key and value select every element of a list of objects
+	// whose key attribute is the string value
+	key   string
+	value string
+}
+
+func (s pathStep) String() string {
+	switch {
+	case s.attr != "" && strings.ContainsAny(s.attr, ".[]="):
+		return fmt.Sprintf("[%q]", s.attr)
+	case s.attr != "":
+		return "." + s.attr
+	case s.key != "":
+		return fmt.Sprintf("[%s=%s]", s.key, s.value)
+	default:
+		return fmt.Sprintf("[%d]", s.index)
+	}
+}
+
+// setExpr replaces the value at path with a raw Terraform expression
+type setExpr struct {
+	path []pathStep
+	expr string
+}
+
+// parsePath parses a path made up of dotted attribute names, list indexes
+// like [0], list selectors like [name=app] and quoted attribute names
+// like ["app.kubernetes.io/name"]
+func parsePath(s string) ([]pathStep, error) {
+	path := []pathStep{}
+	i := 0
+	for i < len(s) {
+		switch s[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed [ in path %q", s)
+			}
+			sel := s[i+1 : i+end]
+			if strings.HasPrefix(sel, `"`) {
+				// quoted keys can contain ] so look for the closing quote instead
+				end = strings.Index(s[i:], `"]`)
+				if end == -1 {
+					return nil, fmt.Errorf("unclosed quote in path %q", s)
+				}
+				attr, err := strconv.Unquote(s[i+1 : i+end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid quoted key in path %q: %s", s, err)
+				}
+				path = append(path, pathStep{attr: attr})
+				i += end + 2
+				continue
+			}
+			if kv := strings.SplitN(sel, "=", 2); len(kv) == 2 {
+				path = append(path, pathStep{key: kv[0], value: kv[1]})
+			} else {
+				n, err := strconv.Atoi(sel)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid list selector [%s] in path %q", sel, s)
+				}
+				path = append(path, pathStep{index: n})
+			}
+			i += end + 1
+		default:
+			end := strings.IndexAny(s[i:], ".[")
+			if end == -1 {
+				end = len(s) - i
+			}
+			path = append(path, pathStep{attr: s[i : i+end]})
+			i += end
+		}
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return path, nil
+}
+
+// parseSetExpr parses a --set-expr argument of the form path=expression
+func parseSetExpr(s string) (setExpr, error) {
+	// the path can contain = inside of list selectors so
+	// split on the first = that is outside of brackets
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '=':
+			if depth > 0 {
+				continue
+			}
+			path, err := parsePath(strings.TrimSpace(s[:i]))
+			if err != nil {
+				return setExpr{}, err
+			}
+			expr := strings.TrimSpace(s[i+1:])
+			if expr == "" {
+				return setExpr{}, fmt.Errorf("missing expression in %q", s)
+			}
+			return setExpr{path: path, expr: expr}, nil
+		}
+	}
+	return setExpr{}, fmt.Errorf("%q must be in the form path=expression", s)
+}
+
+// apply replaces every value matching the path with the expression and
+// returns the new value and whether anything matched
+func (e setExpr) apply(v cty.Value) (cty.Value, bool) {
+	return setPath(v, e.path, terraform.ExpressionVal(e.expr))
+}
+
+func setPath(v cty.Value, path []pathStep, expr cty.Value) (cty.Value, bool) {
+	if len(path) == 0 {
+		return expr, true
+	}
+	if v.IsNull() || !v.IsKnown() {
+		return v, false
+	}
+
+	step := path[0]
+	ty := v.Type()
+	switch {
+	case step.attr != "":
+		if !ty.IsObjectType() || !ty.HasAttribute(step.attr) {
+			return v, false
+		}
+		m := v.AsValueMap()
+		nv, ok := setPath(m[step.attr], path[1:], expr)
+		if !ok {
+			return v, false
+		}
+		m[step.attr] = nv
+		return cty.ObjectVal(m), true
+	case ty.IsTupleType():
+		elems := v.AsValueSlice()
+		matched := false
+		for i, el := range elems {
+			if step.key != "" {
+				if !el.Type().IsObjectType() || !el.Type().HasAttribute(step.key) {
+					continue
+				}
+				k := el.GetAttr(step.key)
+				if k.Type() != cty.String || k.IsNull() || k.AsString() != step.value {
+					continue
+				}
+			} else if i != step.index {
+				continue
+			}
+			nv, ok := setPath(el, path[1:], expr)
+			if ok {
+				elems[i] = nv
+				matched = true
+			}
+		}
+		if !matched {
+			return v, false
+		}
+		return cty.TupleVal(elems), true
+	}
+	return v, false
+}
+
+// applySetExpressions applies every --set-expr to each resource, warning
+// about expressions that did not match anything in the input
+func applySetExpressions(resources []resource, o *options) []resource {
+	for _, e := range o.setExprs {
+		matched := false
+		for i, r := range resources {
+			if doc, ok := e.apply(r.doc); ok {
+				resources[i].doc = doc
+				matched = true
+			}
+		}
+		if !matched {
+			o.warnf("--set-expr path %s did not match any documents", formatPath(e.path))
+		}
+	}
+	return resources
+}
+
+// formatPath turns a parsed path back into a string
+func formatPath(path []pathStep) string {
+	var buf strings.Builder
+	for _, s := range path {
+		buf.WriteString(s.String())
+	}
+	return strings.TrimPrefix(buf.String(), ".")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSetExpr(t *testing.T) {
+	tests := []struct {
+		Arg      string
+		WantPath string
+		WantExpr string
+	}{
+		{
+			`spec.replicas=var.replicas`,
+			`spec.replicas`,
+			`var.replicas`,
+		},
+		{
+			`spec.template.spec.containers[name=app].image = var.image`,
+			`spec.template.spec.containers[name=app].image`,
+			`var.image`,
+		},
+		{
+			`spec.ports[0].port=local.port == 0 ? 80 : local.port`,
+			`spec.ports[0].port`,
+			`local.port == 0 ? 80 : local.port`,
+		},
+		{
+			`metadata.labels["app.kubernetes.io/version"]=var.version`,
+			`metadata.labels["app.kubernetes.io/version"]`,
+			`var.version`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Arg, func(t *testing.T) {
+			e, err := parseSetExpr(test.Arg)
+			if err != nil {
+				t.Fatal("Parsing --set-expr failed:", err)
+			}
+			assert.Equal(t, test.WantPath, formatPath(e.path))
+			assert.Equal(t, test.WantExpr, e.expr)
+		})
+	}
+}
+
+func TestParseSetExprInvalid(t *testing.T) {
+	for _, arg := range []string{
+		`spec.replicas`,
+		`spec.replicas=`,
+		`=var.replicas`,
+		`spec.ports[x].port=80`,
+		`spec.ports[0.port=80`,
+	} {
+		t.Run(arg, func(t *testing.T) {
+			_, err := parseSetExpr(arg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSetExpressions(t *testing.T) {
+	yaml := `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+      - name: sidecar
+        image: sidecar:1.0`
+
+	image, err := parseSetExpr("spec.template.spec.containers[name=app].image=var.image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err := parseSetExpr("spec.replicas=var.replicas")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := bytes.Buffer{}
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithSetExpressions(image, missing), WithWarnings(&warnings))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubernetes_manifest" "deployment_app" {
+  manifest = {
+    "apiVersion" = "apps/v1"
+    "kind" = "Deployment"
+    "metadata" = {
+      "name" = "app"
+    }
+    "spec" = {
+      "template" = {
+        "spec" = {
+          "containers" = [
+            {
+              "image" = var.image
+              "name" = "app"
+            },
+            {
+              "image" = "sidecar:1.0"
+              "name" = "sidecar"
+            },
+          ]
+        }
+      }
+    }
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+	assert.Equal(t,
+		"warning: --set-expr path spec.replicas did not match any documents\n",
+		warnings.String())
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+
+	cty "github.com/zclconf/go-cty/cty"
+	yaml11 "gopkg.in/yaml.v2"
+	yaml12 "gopkg.in/yaml.v3"
+)
+
+// The YAML 1.2 core schema, see https://yaml.org/spec/1.2.2/#1032-tag-resolution
+var (
+	coreNull     = regexp.MustCompile(`^(null|Null|NULL|~|)$`)
+	coreBool     = regexp.MustCompile(`^(true|True|TRUE|false|False|FALSE)$`)
+	coreInt      = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	coreOctal    = regexp.MustCompile(`^0o[0-7]+$`)
+	coreHex      = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	coreFloat    = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	coreInfinity = regexp.MustCompile(`^([-+]?\.(inf|Inf|INF)|\.(nan|NaN|NAN))$`)
+)
+
+// scalarKind is the type a plain scalar resolves to
+type scalarKind string
+
+const (
+	kindNull   scalarKind = "null"
+	kindBool   scalarKind = "boolean"
+	kindInt    scalarKind = "integer"
+	kindFloat  scalarKind = "float"
+	kindString scalarKind = "string"
+)
+
+// resolveCore resolves the type of a plain scalar using the YAML 1.2 core schema
+func resolveCore(s string) scalarKind {
+	switch {
+	case coreNull.MatchString(s):
+		return kindNull
+	case coreBool.MatchString(s):
+		return kindBool
+	case coreInt.MatchString(s), coreOctal.MatchString(s), coreHex.MatchString(s):
+		return kindInt
+	case coreFloat.MatchString(s), coreInfinity.MatchString(s):
+		return kindFloat
+	}
+	return kindString
+}
+
+// resolveLegacy resolves a plain scalar the way sigs.k8s.io/yaml does,
+// which follows YAML 1.1
+func resolveLegacy(s string) (scalarKind, interface{}) {
+	var v interface{}
+	if err := yaml11.Unmarshal([]byte(s), &v); err != nil {
+		return kindString, s
+	}
+	switch v.(type) {
+	case nil:
+		return kindNull, v
+	case bool:
+		return kindBool, v
+	case int, int64, uint64:
+		return kindInt, v
+	case float64:
+		return kindFloat, v
+	}
+	return kindString, v
+}
+
+// strictDecoder turns YAML 1.2 nodes into cty values
+type strictDecoder struct {
+	o *options
+}
+
+// readManifestsStrict parses a stream of YAML documents following the
+// YAML 1.2 core schema. Duplicate keys are an error and numbers are kept
+// exactly as they are written. A warning is written for every plain scalar
+// that would be read differently by the default YAML 1.1 parser.
+func readManifestsStrict(r io.Reader, o *options) ([]cty.Value, error) {
+	d := strictDecoder{o: o}
+	dec := yaml12.NewDecoder(r)
+	manifests := []cty.Value{}
+	for {
+		var node yaml12.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := d.decode(&node)
+		if err != nil {
+			return nil, err
+		}
+
+		if doc.IsNull() {
+			// skip empty YAML docs
+			continue
+		}
+
+		if !doc.Type().IsObjectType() {
+			return nil, fmt.Errorf("the manifest must be a YAML document")
+		}
+
+		manifests = append(manifests, doc)
+	}
+	return manifests, nil
+}
+
+func (d strictDecoder) decode(node *yaml12.Node) (cty.Value, error) {
+	switch node.Kind {
+	case yaml12.DocumentNode:
+		if len(node.Content) == 0 {
+			return cty.NullVal(cty.DynamicPseudoType), nil
+		}
+		return d.decode(node.Content[0])
+	case yaml12.AliasNode:
+		return d.decode(node.Alias)
+	case yaml12.MappingNode:
+		m := map[string]cty.Value{}
+		lines := map[string]int{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if k.Kind != yaml12.ScalarNode {
+				return cty.NilVal, fmt.Errorf("line %d: mapping keys must be scalars", k.Line)
+			}
+			if k.Value == "<<" && k.Tag == "!!merge" {
+				return cty.NilVal, fmt.Errorf("line %d: merge keys are not part of YAML 1.2", k.Line)
+			}
+			if line, ok := lines[k.Value]; ok {
+				return cty.NilVal, fmt.Errorf("line %d: duplicate key %q, first defined on line %d", k.Line, k.Value, line)
+			}
+			lines[k.Value] = k.Line
+			if k.Style == 0 {
+				if kind, _ := resolveLegacy(k.Value); kind == kindBool {
+					d.o.warnf("line %d: key %q is a string in YAML 1.2 but a boolean in YAML 1.1", k.Line, k.Value)
+				}
+			}
+			vv, err := d.decode(v)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			m[k.Value] = vv
+		}
+		return cty.ObjectVal(m), nil
+	case yaml12.SequenceNode:
+		l := []cty.Value{}
+		for _, n := range node.Content {
+			v, err := d.decode(n)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			l = append(l, v)
+		}
+		if len(l) == 0 {
+			return cty.EmptyTupleVal, nil
+		}
+		return cty.TupleVal(l), nil
+	case yaml12.ScalarNode:
+		return d.scalar(node)
+	}
+	return cty.NilVal, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+}
+
+func (d strictDecoder) scalar(node *yaml12.Node) (cty.Value, error) {
+	kind := kindString
+	switch {
+	case node.Style&yaml12.TaggedStyle != 0:
+		switch node.Tag {
+		case "!!null":
+			kind = kindNull
+		case "!!bool":
+			kind = kindBool
+		case "!!int":
+			kind = kindInt
+		case "!!float":
+			kind = kindFloat
+		}
+	case node.Style == 0:
+		kind = resolveCore(node.Value)
+		d.compareLegacy(node, kind)
+	}
+
+	s := node.Value
+	switch kind {
+	case kindNull:
+		return cty.NullVal(cty.DynamicPseudoType), nil
+	case kindBool:
+		return cty.BoolVal(strings.ToLower(s) == "true"), nil
+	case kindInt:
+		i := new(big.Int)
+		var ok bool
+		switch {
+		case coreOctal.MatchString(s):
+			_, ok = i.SetString(s[2:], 8)
+		case coreHex.MatchString(s):
+			_, ok = i.SetString(s[2:], 16)
+		default:
+			_, ok = i.SetString(strings.TrimPrefix(s, "+"), 10)
+		}
+		if !ok {
+			return cty.NilVal, fmt.Errorf("line %d: invalid integer %q", node.Line, s)
+		}
+		return cty.NumberVal(new(big.Float).SetInt(i)), nil
+	case kindFloat:
+		if coreInfinity.MatchString(s) {
+			return cty.NilVal, fmt.Errorf("line %d: %s cannot be represented in Terraform", node.Line, s)
+		}
+		v, err := cty.ParseNumberVal(strings.TrimPrefix(s, "+"))
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("line %d: invalid number %q", node.Line, s)
+		}
+		return v, nil
+	}
+	return cty.StringVal(s), nil
+}
+
+// compareLegacy warns when a plain scalar would be read as a different
+// type or value by the YAML 1.1 parser that is used without --strict
+func (d strictDecoder) compareLegacy(node *yaml12.Node, kind scalarKind) {
+	legacyKind, legacy := resolveLegacy(node.Value)
+	if legacyKind != kind {
+		d.o.warnf("line %d: %q is a %s in YAML 1.2 but a %s in YAML 1.1",
+			node.Line, node.Value, kind, legacyKind)
+		return
+	}
+	if kind == kindInt && coreInt.MatchString(node.Value) {
+		i, _ := new(big.Int).SetString(strings.TrimPrefix(node.Value, "+"), 10)
+		if fmt.Sprint(legacy) != i.String() {
+			d.o.warnf("line %d: %q is %s in YAML 1.2 but %v in YAML 1.1",
+				node.Line, node.Value, i.String(), legacy)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrictYAML(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  labels:
+    country: no
+    enabled: on
+data:
+  mode: "0644"
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: test
+spec:
+  octal: 010
+  newOctal: 0o10
+  hex: 0x1F
+  big: 123456789012345678901234567890
+  precise: 0.1000000000000000055511151231257827
+  yes: yes
+  empty: ~
+  list: []`
+
+	warnings := bytes.Buffer{}
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, true, false,
+		WithStrictYAML(), WithWarnings(&warnings))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `{
+  "apiVersion" = "v1"
+  "data" = {
+    "mode" = "0644"
+  }
+  "kind" = "ConfigMap"
+  "metadata" = {
+    "labels" = {
+      "country" = "no"
+      "enabled" = "on"
+    }
+    "name" = "test"
+  }
+}
+
+{
+  "apiVersion" = "example.com/v1"
+  "kind" = "Widget"
+  "metadata" = {
+    "name" = "test"
+  }
+  "spec" = {
+    "big" = 123456789012345678901234567890
+    "empty" = null
+    "hex" = 31
+    "list" = []
+    "newOctal" = 8
+    "octal" = 10
+    "precise" = 0.1000000000000000055511151231257827
+    "yes" = "yes"
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+	assert.Equal(t, `warning: line 7: "no" is a string in YAML 1.2 but a boolean in YAML 1.1
+warning: line 8: "on" is a string in YAML 1.2 but a boolean in YAML 1.1
+warning: line 17: "010" is 10 in YAML 1.2 but 8 in YAML 1.1
+warning: line 20: "123456789012345678901234567890" is a integer in YAML 1.2 but a float in YAML 1.1
+warning: line 22: key "yes" is a string in YAML 1.2 but a boolean in YAML 1.1
+warning: line 22: "yes" is a string in YAML 1.2 but a boolean in YAML 1.1
+`, warnings.String())
+}
+
+func TestStrictYAMLDuplicateKeys(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  KEY: one
+  KEY: two`
+
+	r := strings.NewReader(yaml)
+	_, err := YAMLToTerraformResources(r, "", false, false, false, WithStrictYAML())
+
+	if assert.Error(t, err) {
+		assert.Equal(t, `line 8: duplicate key "KEY", first defined on line 7`, err.Error())
+	}
+}
+
+func TestStrictYAMLInfinity(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+spec:
+  limit: .inf`
+
+	r := strings.NewReader(yaml)
+	_, err := YAMLToTerraformResources(r, "", false, false, false, WithStrictYAML())
+
+	assert.Error(t, err)
+}
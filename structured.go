@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	cty "github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	yaml12 "gopkg.in/yaml.v3"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// structuredValue parses a string that holds a JSON or YAML document and
+// returns a jsonencode() or yamlencode() call that produces it. Only
+// objects and arrays are considered, and only when Terraform would encode
+// the document back into exactly the same string, since anything else
+// would change the data. YAML is read with the YAML 1.2 core schema, and
+// documents with scalars that YAML 1.1 reads differently are left alone.
+func structuredValue(s string) (cty.Value, bool) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") && !strings.Contains(trimmed, "\n") {
+		return cty.NilVal, false
+	}
+
+	if json.Valid([]byte(trimmed)) {
+		t, err := ctyjson.ImpliedType([]byte(trimmed))
+		if err != nil {
+			return cty.NilVal, false
+		}
+		v, err := ctyjson.Unmarshal([]byte(trimmed), t)
+		if err != nil || !(t.IsObjectType() || t.IsTupleType()) {
+			return cty.NilVal, false
+		}
+		encoded, err := ctyjson.Marshal(v, t)
+		if err != nil || string(encoded) != s {
+			return cty.NilVal, false
+		}
+		return terraform.FunctionCallVal("jsonencode", v), true
+	}
+
+	var node yaml12.Node
+	if err := yaml12.Unmarshal([]byte(s), &node); err != nil {
+		return cty.NilVal, false
+	}
+	// a scalar YAML 1.1 reads differently is ambiguous,
+	// which the decoder reports as a warning
+	var warnings bytes.Buffer
+	d := strictDecoder{o: &options{warnings: &warnings}}
+	v, err := d.decode(&node)
+	if err != nil || warnings.Len() > 0 || v.IsNull() {
+		return cty.NilVal, false
+	}
+	if t := v.Type(); !(t.IsObjectType() || t.IsTupleType()) {
+		return cty.NilVal, false
+	}
+	encoded, err := ctyyaml.Standard.Marshal(v)
+	if err != nil || string(encoded) != s {
+		return cty.NilVal, false
+	}
+	return terraform.FunctionCallVal("yamlencode", v), true
+}
+
+// encodeStructuredData replaces ConfigMap data and Secret stringData
+// values that hold JSON or YAML documents with jsonencode() or
+// yamlencode() calls so that they are written as native HCL
+func encodeStructuredData(resources []resource) []resource {
+	for i, r := range resources {
+		var field string
+		switch getString(r.doc, "kind") {
+		case "ConfigMap":
+			field = "data"
+		case "Secret":
+			field = "stringData"
+		default:
+			continue
+		}
+		resources[i].doc = updateAttr(r.doc, false, func(data cty.Value) cty.Value {
+			if data == cty.NilVal || data.IsNull() || !data.Type().IsObjectType() {
+				return data
+			}
+			m := valueMap(data)
+			for k, v := range m {
+				if v.Type() != cty.String || v.IsNull() {
+					continue
+				}
+				if structured, ok := structuredValue(v.AsString()); ok {
+					m[k] = structured
+				}
+			}
+			return cty.ObjectVal(m)
+		}, field)
+	}
+	return resources
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructuredData(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: grafana
+data:
+  panels.json: '{"panels":[{"id":1,"type":"graph"}],"refresh":"${REFRESH}","title":"Cluster"}'
+  dashboard.json: |
+    {
+      "title": "Cluster"
+    }
+  prometheus.yml: |
+    "global":
+      "scrape_interval": "15s"
+    "scrape_configs":
+    - "job_name": "kubernetes"
+  notes.txt: |
+    some notes
+    over two lines
+`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithStructuredData())
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	// dashboard.json is not written the way jsonencode() writes it
+	expected := `
+resource "kubernetes_manifest" "configmap_grafana" {
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      "dashboard.json" = <<-EOT
+      {
+        "title": "Cluster"
+      }
+      EOT
+      "notes.txt" = <<-EOT
+      some notes
+      over two lines
+      EOT
+      "panels.json" = jsonencode({
+        "panels" = [
+          {
+            "id" = 1
+            "type" = "graph"
+          },
+        ]
+        "refresh" = "$${REFRESH}"
+        "title" = "Cluster"
+      })
+      "prometheus.yml" = yamlencode({
+        "global" = {
+          "scrape_interval" = "15s"
+        }
+        "scrape_configs" = [
+          {
+            "job_name" = "kubernetes"
+          },
+        ]
+      })
+    }
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "grafana"
+    }
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestStructuredDataSecretWithoutStringData(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+data:
+  key: aGVsbG8=
+`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithStructuredData())
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubernetes_manifest" "secret_test" {
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      "key" = "aGVsbG8="
+    }
+    "kind" = "Secret"
+    "metadata" = {
+      "name" = "test"
+    }
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestStructuredValue(t *testing.T) {
+	tests := []struct {
+		Value string
+		Want  bool
+	}{
+		{`{"a":1,"b":[true,null]}`, true},
+		{`[1,2,3]`, true},
+		{"\"a\": 1\n\"b\":\n- \"c\"\n", true},
+		// jsonencode() writes JSON without spaces
+		{`{"a": 1, "b": [true, null]}`, false},
+		// yamlencode() quotes keys and strings
+		{"a: 1\nb:\n- c\n", false},
+		{`"just a string"`, false},
+		{`123`, false},
+		{"plain text", false},
+		{"\"a\": 1 # with a comment\n\"b\": 2\n", false},
+		{"\"a\": &anchor 1\n\"b\": *anchor\n", false},
+		// 1.0 would be written back out as 1
+		{`{"a":1.0}`, false},
+		// scalars YAML 1.1 and YAML 1.2 read differently
+		{"country: no\nmode: 0755\nenabled: on\nversion: 1.10\n", false},
+		{"\"country\": no\n\"other\": \"x\"\n", false},
+		{"\"enabled\": on\n\"other\": \"x\"\n", false},
+		{"\"mode\": 0755\n\"other\": \"x\"\n", false},
+		{"\"version\": 1.10\n\"other\": \"x\"\n", false},
+		{"\"country\": \"no\"\n\"enabled\": \"on\"\n\"mode\": \"0755\"\n\"version\": \"1.10\"\n", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Value, func(t *testing.T) {
+			_, ok := structuredValue(test.Value)
+			assert.Equal(t, test.Want, ok)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tool is the program that will run the generated configuration
+type tool string
+
+const (
+	toolTerraform tool = "terraform"
+	toolOpenTofu  tool = "opentofu"
+)
+
+// version is a major and minor version, the zero value means unsupported
+type version struct {
+	major, minor int
+}
+
+func (v version) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+// atLeast returns true if v is the same as or newer than other
+func (v version) atLeast(other version) bool {
+	if v.major != other.major {
+		return v.major > other.major
+	}
+	return v.minor >= other.minor
+}
+
+// target is the tool and version the output has to work with
+type target struct {
+	tool    tool
+	version version
+}
+
+// defaultTarget is the oldest version that can import existing objects
+// with import blocks
+var defaultTarget = target{tool: toolTerraform, version: version{1, 5}}
+
+func (t target) String() string {
+	return fmt.Sprintf("%s@%s", t.tool, t.version)
+}
+
+// command returns the name of the command line tool
+func (t target) command() string {
+	if t.tool == toolOpenTofu {
+		return "tofu"
+	}
+	return "terraform"
+}
+
+// parseTarget parses a target such as terraform@1.5 or opentofu@1.9
+func parseTarget(s string) (target, error) {
+	parts := strings.SplitN(s, "@", 2)
+	if len(parts) != 2 {
+		return target{}, fmt.Errorf("target %q must be in the form tool@version, e.g. terraform@1.5", s)
+	}
+
+	t := target{tool: tool(parts[0])}
+	if t.tool != toolTerraform && t.tool != toolOpenTofu {
+		return target{}, fmt.Errorf("unknown tool %q in target, must be one of: terraform, opentofu", parts[0])
+	}
+
+	// patch versions don't change the language so they are ignored
+	numbers := strings.SplitN(parts[1], ".", 3)
+	if len(numbers) < 2 {
+		return target{}, fmt.Errorf("target version %q must include the minor version, e.g. 1.5", parts[1])
+	}
+	var err error
+	t.version.major, err = strconv.Atoi(numbers[0])
+	if err != nil {
+		return target{}, fmt.Errorf("invalid target version %q", parts[1])
+	}
+	t.version.minor, err = strconv.Atoi(numbers[1])
+	if err != nil {
+		return target{}, fmt.Errorf("invalid target version %q", parts[1])
+	}
+	return t, nil
+}
+
+// feature is a language feature that is only available in newer versions
+type feature struct {
+	name string
+
+	// terraform and opentofu are the first versions with the feature
+	terraform version
+	opentofu  version
+}
+
+var (
+	// featureImportBlocks is import blocks with a literal id
+	featureImportBlocks = feature{"import blocks", version{1, 5}, version{1, 6}}
+
+	// featureImportExpressions is import blocks with an id that uses expressions
+	featureImportExpressions = feature{"expressions in import ids", version{1, 6}, version{1, 6}}
+
+	// featureImportForEach is import blocks with for_each
+	featureImportForEach = feature{"import blocks with for_each", version{1, 7}, version{1, 7}}
+
+	// featureRemovedBlocks is removed blocks for forgetting resources
+	// without destroying them
+	featureRemovedBlocks = feature{"removed blocks", version{1, 7}, version{1, 7}}
+
+	// featureProviderFunctions is provider-defined functions such
+	// as provider::kubernetes::manifest_decode
+	featureProviderFunctions = feature{"provider-defined functions", version{1, 8}, version{1, 7}}
+
+	// featureProviderIteration is for_each in provider blocks
+	featureProviderIteration = feature{"provider iteration", version{}, version{1, 9}}
+)
+
+// supports returns true if the target can use the feature
+func (t target) supports(f feature) bool {
+	first := f.terraform
+	if t.tool == toolOpenTofu {
+		first = f.opentofu
+	}
+	if first == (version{}) {
+		return false
+	}
+	return t.version.atLeast(first)
+}
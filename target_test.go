@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTarget(t *testing.T) {
+	for s, want := range map[string]target{
+		"terraform@1.5":   {toolTerraform, version{1, 5}},
+		"terraform@1.3.9": {toolTerraform, version{1, 3}},
+		"opentofu@1.9":    {toolOpenTofu, version{1, 9}},
+	} {
+		t.Run(s, func(t *testing.T) {
+			got, err := parseTarget(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestParseTargetInvalid(t *testing.T) {
+	for _, s := range []string{"terraform", "pulumi@3.0", "terraform@1", "terraform@one.two"} {
+		t.Run(s, func(t *testing.T) {
+			_, err := parseTarget(s)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTargetSupports(t *testing.T) {
+	tests := []struct {
+		target   string
+		feature  feature
+		supports bool
+	}{
+		{"terraform@1.3", featureImportBlocks, false},
+		{"terraform@1.5", featureImportBlocks, true},
+		{"terraform@1.5", featureImportExpressions, false},
+		{"terraform@1.6", featureImportExpressions, true},
+		{"terraform@2.0", featureImportBlocks, true},
+		{"terraform@1.6", featureRemovedBlocks, false},
+		{"terraform@1.7", featureRemovedBlocks, true},
+		{"terraform@1.7", featureProviderFunctions, false},
+		{"terraform@1.8", featureProviderFunctions, true},
+		{"terraform@1.6", featureImportForEach, false},
+		{"terraform@2.0", featureImportForEach, true},
+		{"terraform@1.9", featureProviderIteration, false},
+		{"opentofu@1.7", featureProviderFunctions, true},
+		{"opentofu@1.5", featureImportBlocks, false},
+		{"opentofu@1.6", featureImportBlocks, true},
+		{"opentofu@1.8", featureProviderIteration, false},
+		{"opentofu@1.9", featureProviderIteration, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.target+" "+test.feature.name, func(t *testing.T) {
+			target, err := parseTarget(test.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.supports, target.supports(test.feature))
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime/debug"
 	"strings"
@@ -22,9 +24,6 @@ import (
 // toolVersion is the version that gets printed when you run --version
 var toolVersion string
 
-// resourceType is the type of Terraform resource
-var resourceType = "kubernetes_manifest"
-
 // ignoreMetadata is the list of metadata fields to strip
 // when --strip is supplied
 var ignoreMetadata = []string{
@@ -82,99 +81,175 @@ func stripServerSideFields(doc cty.Value) cty.Value {
 	return cty.ObjectVal(m)
 }
 
+// generatedKinds are the kinds whose objects are always created by the
+// cluster, which are skipped when --skip-generated is supplied
+var generatedKinds = []string{
+	"Event",
+	"Endpoints",
+	"EndpointSlice",
+	"ControllerRevision",
+}
+
+// generatedObjects are well-known objects the cluster creates, which are
+// skipped when --skip-generated is supplied. An empty namespace matches
+// any namespace.
+var generatedObjects = []struct {
+	kind, namespace, name string
+}{
+	{"ConfigMap", "", "kube-root-ca.crt"},
+	{"ServiceAccount", "", "default"},
+	{"Service", "default", "kubernetes"},
+	{"Namespace", "", "default"},
+	{"Namespace", "", "kube-system"},
+	{"Namespace", "", "kube-public"},
+	{"Namespace", "", "kube-node-lease"},
+}
+
+// isGenerated returns true for objects that are created by a controller
+// or by the cluster itself, which Terraform would fight over
+func isGenerated(doc cty.Value) bool {
+	kind := getString(doc, "kind")
+	if contains(generatedKinds, kind) {
+		return true
+	}
+	if kind == "Secret" && isGeneratedToken(doc) {
+		return true
+	}
+
+	name := getString(doc, "metadata", "name")
+	namespace := getString(doc, "metadata", "namespace")
+	for _, g := range generatedObjects {
+		// --strip removes the default namespace, so a missing one matches it
+		if g.kind == kind && g.name == name &&
+			(g.namespace == "" || g.namespace == namespace || (g.namespace == "default" && namespace == "")) {
+			return true
+		}
+	}
+
+	refs, ok := getAttr(doc, "metadata", "ownerReferences")
+	if !ok || refs.IsNull() || !(refs.Type().IsTupleType() || refs.Type().IsListType()) {
+		return false
+	}
+	for _, ref := range refs.AsValueSlice() {
+		if controller, ok := getAttr(ref, "controller"); ok && controller.Type() == cty.Bool && !controller.IsNull() && controller.True() {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedTokenSuffix is the random suffix the token controller of
+// Kubernetes before 1.24 added to the name of a ServiceAccount
+var generatedTokenSuffix = regexp.MustCompile(`^-token-[bcdfghjklmnpqrstvwxz2456789]{5}$`)
+
+// isGeneratedToken returns true for the service account token Secrets that
+// Kubernetes before 1.24 created for every ServiceAccount. Token Secrets
+// that users create are named by them, so they are kept.
+func isGeneratedToken(doc cty.Value) bool {
+	if getString(doc, "type") != "kubernetes.io/service-account-token" {
+		return false
+	}
+	account := getString(doc, "metadata", "annotations", "kubernetes.io/service-account.name")
+	name := getString(doc, "metadata", "name")
+	return account != "" && strings.HasPrefix(name, account) &&
+		generatedTokenSuffix.MatchString(strings.TrimPrefix(name, account))
+}
+
 // snakify converts "a-String LIKE this" to "a_string_like_this"
 func snakify(s string) string {
 	re := regexp.MustCompile(`\W`)
 	return strings.ToLower(re.ReplaceAllString(s, "_"))
 }
 
-// escape incidences of ${} with $${} to prevent Terraform trying to interpolate them
-func escapeShellVars(s string) string {
-	r := regexp.MustCompile(`(\${.*?)`)
-	return r.ReplaceAllString(s, `$$$1`)
+// resource is a single Kubernetes object together with the name of the
+// Terraform resource it will be written as
+type resource struct {
+	name string
+	doc  cty.Value
+
+	// original is the document before values were replaced with
+	// function calls, which --verify compares the calls with
+	original cty.Value
+
+	// files are written alongside the Terraform config, keyed
+	// by their path relative to the output directory
+	files map[string][]byte
 }
 
-// yamlToHCL converts a single YAML document Terraform HCL
-func yamlToHCL(
-	doc cty.Value, providerAlias string,
-	stripServerSide bool, mapOnly bool, stripKeyQuotes bool) (string, error) {
-	m := doc.AsValueMap()
-	docs := []cty.Value{doc}
-	if strings.HasSuffix(m["kind"].AsString(), "List") {
-		docs = m["items"].AsValueSlice()
+// expandList returns the items of a *List kind, including the items of
+// Lists nested in it, or the document itself
+func expandList(doc cty.Value) []cty.Value {
+	items, ok := getAttr(doc, "items")
+	if !strings.HasSuffix(getString(doc, "kind"), "List") || !ok || items.IsNull() ||
+		!(items.Type().IsTupleType() || items.Type().IsListType()) {
+		return []cty.Value{doc}
 	}
-
-	hcl := ""
-	for i, doc := range docs {
-		mm := doc.AsValueMap()
-		kind := mm["kind"].AsString()
-		metadata := mm["metadata"].AsValueMap()
-		var namespace string
-		if v, ok := metadata["namespace"]; ok {
-			namespace = v.AsString()
-		}
-
-		var name string
-		if n, ok := metadata["name"]; ok {
-			name = n.AsString()
-		} else if n, ok := metadata["generateName"]; ok {
-			name = n.AsString()
-			if name[len(name)-1] == '-' {
-				name = name[:len(name)-1]
-			}
+	docs := []cty.Value{}
+	for _, item := range items.AsValueSlice() {
+		if !item.IsNull() && item.Type().IsObjectType() {
+			docs = append(docs, expandList(item)...)
 		}
+	}
+	return docs
+}
 
-		resourceName := kind
-		if namespace != "" && namespace != "default" {
-			resourceName = resourceName + "_" + namespace
-		}
-		resourceName = resourceName + "_" + name
-		resourceName = snakify(resourceName)
+// resourceName builds the Terraform resource name for a document
+// from its kind, namespace and name
+func resourceName(doc cty.Value) string {
+	mm := doc.AsValueMap()
+	kind := mm["kind"].AsString()
+	metadata := mm["metadata"].AsValueMap()
+	var namespace string
+	if v, ok := metadata["namespace"]; ok {
+		namespace = v.AsString()
+	}
 
-		if stripServerSide {
-			doc = stripServerSideFields(doc)
+	var name string
+	if n, ok := metadata["name"]; ok {
+		name = n.AsString()
+	} else if n, ok := metadata["generateName"]; ok {
+		name = n.AsString()
+		if name[len(name)-1] == '-' {
+			name = name[:len(name)-1]
 		}
-		s := terraform.FormatValue(doc, 0, stripKeyQuotes)
-		s = escapeShellVars(s)
+	}
 
-		if mapOnly {
-			hcl += fmt.Sprintf("%v\n", s)
-		} else {
-			hcl += fmt.Sprintf("resource %q %q {\n", resourceType, resourceName)
-			if providerAlias != "" {
-				hcl += fmt.Sprintf("  provider = %v\n\n", providerAlias)
-			}
-			hcl += fmt.Sprintf("  manifest = %v\n", strings.ReplaceAll(s, "\n", "\n  "))
-			hcl += "}\n"
-		}
-		if i != len(docs)-1 {
-			hcl += "\n"
-		}
+	resourceName := kind
+	if namespace != "" && namespace != "default" {
+		resourceName = resourceName + "_" + namespace
+	}
+	resourceName = resourceName + "_" + name
+	return snakify(resourceName)
+}
+
+// yamlToHCL converts a single resource to Terraform HCL
+func yamlToHCL(r resource, o *options) (string, error) {
+	if o.mapOnly {
+		s := terraform.FormatValueWithOptions(r.doc, 0, o.formatOptions())
+		return fmt.Sprintf("%v\n", s), nil
 	}
 
+	hcl := fmt.Sprintf("resource %q %q {\n", o.backend.resourceType, r.name)
+	if provider := resourceProvider(o); provider != "" {
+		hcl += fmt.Sprintf("  provider = %v\n\n", provider)
+	}
+	hcl += o.backend.body(r, o)
+	hcl += "}\n"
 	return hcl, nil
 }
 
 var yamlSeparator = "\n---"
 
-// YAMLToTerraformResources takes a file containing one or more Kubernetes configs
-// and converts it to resources that can be used by the Terraform Kubernetes Provider
-//
-// FIXME this function has too many arguments now, use functional options instead
-func YAMLToTerraformResources(
-	r io.Reader, providerAlias string, stripServerSide bool,
-	mapOnly bool, stripKeyQuotes bool) (string, error) {
-	hcl := ""
-
+// readManifests parses a stream of YAML documents, skipping empty ones
+func readManifests(r io.Reader) ([]cty.Value, error) {
 	buf := bytes.Buffer{}
 	_, err := buf.ReadFrom(r)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	count := 0
-	manifest := buf.String()
-	docs := strings.Split(manifest, yamlSeparator)
+	manifests := []cty.Value{}
+	docs := strings.Split(buf.String(), yamlSeparator)
 	for _, doc := range docs {
 		if strings.TrimSpace(doc) == "" {
 			// some manifests have empty documents
@@ -184,17 +259,17 @@ func YAMLToTerraformResources(
 		var b []byte
 		b, err = yaml.YAMLToJSON([]byte(doc))
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		t, err := ctyjson.ImpliedType(b)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		doc, err := ctyjson.Unmarshal(b, t)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		if doc.IsNull() {
@@ -203,24 +278,168 @@ func YAMLToTerraformResources(
 		}
 
 		if !doc.Type().IsObjectType() {
-			return "", fmt.Errorf("the manifest must be a YAML document")
+			return nil, fmt.Errorf("the manifest must be a YAML document")
 		}
 
-		formatted, err := yamlToHCL(doc, providerAlias, stripServerSide, mapOnly, stripKeyQuotes)
+		manifests = append(manifests, doc)
+	}
+
+	return manifests, nil
+}
+
+// convertManifests reads the manifests and turns them into resources,
+// applying all of the transformations configured in the options
+func convertManifests(r io.Reader, o *options) ([]resource, error) {
+	var manifests []cty.Value
+	var err error
+	r, isJSON := jsonInput(r)
+	switch {
+	case o.strict:
+		// JSON is YAML 1.2, so it gets the same checks
+		manifests, err = readManifestsStrict(r, o)
+	case isJSON:
+		manifests, err = readManifestsJSON(r)
+	default:
+		manifests, err = readManifests(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []resource{}
+	for _, m := range manifests {
+		for _, doc := range expandList(m) {
+			if !includeDocument(doc, o) || (o.skipGenerated && isGenerated(doc)) {
+				continue
+			}
+			r := resource{
+				name: resourceName(doc),
+				doc:  doc,
+			}
+			if o.stripServerSide {
+				r.doc = stripServerSideFields(r.doc)
+			}
+			if err := checkUTF8(r.doc, nil); err != nil {
+				return nil, fmt.Errorf("%s: %s", r.name, err)
+			}
+			resources = append(resources, r)
+		}
+	}
+
+	if o.namespace != cty.NilVal {
+		// duplicates are found among the objects in the new namespace,
+		// which also names them unless the namespace is an expression
+		resources = overrideNamespace(resources, o)
+		if o.namespace.Type() == cty.String {
+			for i, r := range resources {
+				resources[i].name = resourceName(r.doc)
+			}
+		}
+	}
+
+	if !o.mapOnly {
+		resources, err = resolveDuplicates(resources, o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(o.labels) > 0 || len(o.annotations) > 0 {
+		resources = addCommonMetadata(resources, o)
+	}
+
+	if len(o.setExprs) > 0 {
+		resources = applySetExpressions(resources, o)
+	}
+
+	for i, r := range resources {
+		resources[i].original = r.doc
+	}
+
+	if o.externalizeThreshold > 0 {
+		resources, err = externalizeFiles(resources, o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.decodeSecrets {
+		resources = decodeSecrets(resources)
+	}
+
+	if o.encodeStructured {
+		resources = encodeStructuredData(resources)
+	}
+
+	return resources, nil
+}
+
+// formatResources converts each resource to HCL
+func formatResources(resources []resource, o *options) (string, error) {
+	if o.manifestDecode && !o.target.supports(featureProviderFunctions) {
+		o.warnf("%s does not support %s, decoding the manifests with yamldecode() instead", o.target, featureProviderFunctions.name)
+	}
+
+	hcl := ""
+	for i, r := range resources {
+		formatted, err := yamlToHCL(r, o)
 		if err != nil {
 			return "", fmt.Errorf("error converting YAML to HCL: %s", err)
 		}
 
-		if count > 0 {
+		if i > 0 {
 			hcl += "\n"
 		}
 		hcl += formatted
-		count++
 	}
 
+	if o.verify {
+		err := verifyResources(hcl, resources, o)
+		if err != nil {
+			return "", err
+		}
+	}
+	return hcl, nil
+}
+
+// formatConfig converts each resource to HCL, preceded by the
+// provider blocks when there are any
+func formatConfig(resources []resource, o *options) (string, error) {
+	if o.language != nil {
+		return formatCDKTF(resources, o)
+	}
+	hcl, err := formatResources(resources, o)
+	if err != nil {
+		return "", err
+	}
+	if len(o.providers) > 0 && !o.mapOnly {
+		hcl = formatProviders(o) + "\n" + hcl
+	}
+	if o.imports && !o.mapOnly {
+		if imports := formatImports(resources, o, "", ""); imports != "" {
+			hcl += "\n" + imports
+		}
+	}
 	return hcl, nil
 }
 
+// YAMLToTerraformResources takes a file containing one or more Kubernetes configs
+// and converts it to resources that can be used by the Terraform Kubernetes Provider
+//
+// FIXME the positional arguments should move to Options as well
+func YAMLToTerraformResources(
+	r io.Reader, providerAlias string, stripServerSide bool,
+	mapOnly bool, stripKeyQuotes bool, opts ...Option) (string, error) {
+	o := newOptions(providerAlias, stripServerSide, mapOnly, stripKeyQuotes, opts)
+
+	resources, err := convertManifests(r, o)
+	if err != nil {
+		return "", err
+	}
+
+	return formatConfig(resources, o)
+}
+
 func capturePanic() {
 	if r := recover(); r != nil {
 		fmt.Printf(
@@ -235,46 +454,437 @@ func capturePanic() {
 	}
 }
 
+// openInput opens the manifests at path, which is - for stdin, a file or
+// a directory whose .yaml and .yml files are read in lexical order
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return os.Stdin, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return os.Open(path)
+	}
+
+	docs := []string{}
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := filepath.Ext(p)
+		if d.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, string(b))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(strings.Join(docs, yamlSeparator+"\n"))), nil
+}
+
+// writeFile writes the content to a file unless it already has exactly
+// that content, so that --watch only touches the files that changed
+func writeFile(filename string, content []byte) error {
+	if existing, err := os.ReadFile(filename); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+	return os.WriteFile(filename, content, 0644)
+}
+
 func main() {
 	defer capturePanic()
 
-	infile := flag.StringP("file", "f", "-", "Input file containing Kubernetes YAML manifests")
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
+	// tfk8s diff takes the same flags as converting the manifests
+	args := os.Args[1:]
+	diffMode := len(args) > 0 && args[0] == "diff"
+	if diffMode {
+		args = args[1:]
+	}
+
+	infile := flag.StringP("file", "f", "-", "Input file or directory containing Kubernetes YAML manifests")
 	outfile := flag.StringP("output", "o", "-", "Output file to write Terraform config")
 	providerAlias := flag.StringP("provider", "p", "", "Provider alias to populate the `provider` attribute")
 	stripServerSide := flag.BoolP("strip", "s", false, "Strip out server side fields - use if you are piping from kubectl get")
+	skipGenerated := flag.Bool("skip-generated", false, "Skip objects owned by a controller and objects the cluster creates, such as Events and the default ServiceAccount")
 	version := flag.BoolP("version", "V", false, "Show tool version")
 	mapOnly := flag.BoolP("map-only", "M", false, "Output only an HCL map structure")
 	stripKeyQuotes := flag.BoolP("strip-key-quotes", "Q", false, "Strip out quotes from HCL map keys unless they are required.")
-	flag.Parse()
+	duplicates := flag.String("duplicates", "error", "How to handle documents that produce the same resource name: error, suffix or group")
+	namespace := flag.StringP("namespace", "n", "", "Set the namespace of every namespaced object")
+	namespaceExpr := flag.String("namespace-expr", "", "Set the namespace of every namespaced object to a Terraform expression, e.g. var.namespace")
+	includeKinds := flag.StringSlice("include-kind", nil, "Only convert documents of these kinds")
+	excludeKinds := flag.StringSlice("exclude-kind", nil, "Skip documents of these kinds")
+	namespaceFilter := flag.StringSlice("namespace-filter", nil, "Only convert documents in these namespaces")
+	labelSelector := flag.StringP("selector", "l", "", "Only convert documents whose labels match this selector, e.g. app=web,env in (prod,staging)")
+	clusterScopedKinds := flag.StringSlice("cluster-scoped-kind", nil, "Additional kinds that should not be given a namespace")
+	labels := flag.StringArray("label", nil, "Add a label to every object, in the form key=value")
+	annotations := flag.StringArray("annotation", nil, "Add an annotation to every object, in the form key=value")
+	templateMetadata := flag.Bool("template-metadata", false, "Also add --label and --annotation to pod templates and CronJob job templates")
+	selectorLabels := flag.Bool("selector-labels", false, "Also add --label to workload selectors, implies --template-metadata")
+	setExprs := flag.StringArray("set-expr", nil, "Replace the value at a path with a Terraform expression, e.g. spec.template.spec.containers[name=app].image=var.image")
+	decodeSecrets := flag.Bool("decode-secrets", false, "Write readable Secret data as base64encode() calls on the decoded value")
+	structuredData := flag.Bool("structured-data", false, "Write JSON and YAML documents in ConfigMaps as jsonencode() and yamlencode() calls")
+	escaping := flag.String("escape", "literal", "How to escape ${ and %{ in strings: literal, preserve (leave $${ and %%{ as they are) or interpolate (let Terraform interpolate them)")
+	strict := flag.Bool("strict", false, "Parse YAML using YAML 1.2 rules, reject duplicate keys and warn about values YAML 1.1 would read differently")
+	heredoc := flag.String("heredoc", "auto", "When to write multi-line strings as heredocs: never, auto (when they read back exactly) or always (using chomp() if needed)")
+	kubeconfigPath := flag.String("provider-from-kubeconfig", "", "Write a provider block for kubeconfig contexts and use it for every resource")
+	kubeContexts := flag.StringSlice("kube-context", nil, "Contexts to write provider blocks for with --provider-from-kubeconfig, the first is used by the resources (default current context)")
+	inlineCluster := flag.Bool("inline-cluster", false, "Write the host and CA certificate of the cluster into the provider blocks instead of pointing at the kubeconfig")
+	targetVersion := flag.String("target", defaultTarget.String(), "Tool and version the output has to work with, e.g. terraform@1.3 or opentofu@1.9")
+	imports := flag.Bool("import", false, "Write an import block for every resource to adopt existing objects")
+	manifestDecode := flag.Bool("manifest-decode", false, "Write manifests as YAML decoded by provider::kubernetes::manifest_decode, or by yamldecode when the target does not support provider-defined functions")
+	moduleDir := flag.String("module-dir", "", "Write a complete Terraform module to this directory instead of a single file")
+	crdStage := flag.String("crd-stage", "", "Write the CustomResourceDefinitions and everything else to separate configurations under this directory, to be applied one after the other")
+	crdStageWebhooks := flag.Bool("crd-stage-webhooks", false, "Also put webhook configurations into the CRD stage of --crd-stage")
+	moduleNamespaces := flag.StringSlice("module-namespace", nil, "Also write a root module that calls the module for each of these namespaces, under namespaces/ in --module-dir")
+	moduleForEach := flag.Bool("module-for-each", false, "Write a single root module under namespaces/ that calls the module for every --module-namespace with for_each, instead of a root module per namespace")
+	providerVersion := flag.String("provider-version", "", "Version constraint for the provider in the versions.tf of --module-dir (default depends on --backend)")
+	outputFormat := flag.String("format", "hcl", "Output format: hcl, cdktf-typescript, cdktf-python or cdktf-go")
+	verify := flag.Bool("verify", false, "Parse the HCL that is written and fail if any manifest does not read back as the input")
+	backendName := flag.String("backend", "manifest", "Resource type to write: manifest (kubernetes_manifest), kubectl (kubectl_manifest) or k8s (k8s_manifest)")
+	externalizeThreshold := flag.Int("externalize-threshold", 0, "Move ConfigMap and Secret values of at least this many bytes into files next to the output")
+	watch := flag.Bool("watch", false, "Convert the manifests again each time the input file or directory changes, until interrupted")
+	update := flag.String("update", "", "Rewrite the manifests of the matching resources in this .tf file and add the new ones, leaving the rest of the file as it is")
+	prune := flag.Bool("prune", false, "With --update, replace the resources that are no longer in the manifests with removed blocks, so the objects stay in the cluster")
+	against := flag.String("against", "", "Directory of Terraform configuration that tfk8s diff compares the manifests with")
+	flag.CommandLine.Parse(args)
 
 	if *version {
 		fmt.Println(toolVersion)
 		os.Exit(0)
 	}
 
-	var file *os.File
-	if *infile == "-" {
-		file = os.Stdin
-	} else {
-		var err error
-		file, err = os.Open(*infile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+	if diffMode {
+		switch {
+		case *against == "":
+			fmt.Fprintf(os.Stderr, "error: tfk8s diff needs --against\r\n")
+			os.Exit(1)
+		case *outfile != "-", *moduleDir != "", *mapOnly, *outputFormat != "hcl":
+			fmt.Fprintf(os.Stderr, "error: tfk8s diff can't be used with --output, --module-dir, --map-only or --format\r\n")
+			os.Exit(1)
+		}
+	} else if *against != "" {
+		fmt.Fprintf(os.Stderr, "error: --against can only be used with tfk8s diff\r\n")
+		os.Exit(1)
+	}
+	if *watch {
+		switch {
+		case diffMode:
+			fmt.Fprintf(os.Stderr, "error: --watch can't be used with tfk8s diff\r\n")
+			os.Exit(1)
+		case *infile == "-":
+			fmt.Fprintf(os.Stderr, "error: --watch needs --file\r\n")
+			os.Exit(1)
+		case *outfile == "-" && *moduleDir == "" && *update == "" && *crdStage == "":
+			fmt.Fprintf(os.Stderr, "error: --watch needs --output, --module-dir, --update or --crd-stage\r\n")
+			os.Exit(1)
+		}
+	}
+	if *update != "" {
+		switch {
+		case diffMode, *outfile != "-", *moduleDir != "", *mapOnly, *outputFormat != "hcl", *imports:
+			fmt.Fprintf(os.Stderr, "error: --update can't be used with tfk8s diff, --output, --module-dir, --map-only, --format or --import\r\n")
 			os.Exit(1)
 		}
 	}
 
-	hcl, err := YAMLToTerraformResources(
-		file, *providerAlias, *stripServerSide, *mapOnly, *stripKeyQuotes)
+	if *prune && *update == "" {
+		fmt.Fprintf(os.Stderr, "error: --prune needs --update\r\n")
+		os.Exit(1)
+	}
+
+	duplicateStrategy, err := parseDuplicateStrategy(*duplicates)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+		os.Exit(1)
+	}
+
+	escapingMode, err := terraform.ParseEscaping(*escaping)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+		os.Exit(1)
+	}
+
+	heredocPolicy, err := terraform.ParseHeredoc(*heredoc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+		os.Exit(1)
+	}
+
+	opts := []Option{
+		WithDuplicateStrategy(duplicateStrategy),
+		WithClusterScopedKinds(*clusterScopedKinds...),
+		WithExternalizeThreshold(*externalizeThreshold),
+		WithEscaping(escapingMode),
+		WithHeredoc(heredocPolicy),
+		WithWarnings(os.Stderr),
+	}
+	labelValues, err := parseKeyValues(*labels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: --label: %s\r\n", err.Error())
+		os.Exit(1)
+	}
+	annotationValues, err := parseKeyValues(*annotations)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: --annotation: %s\r\n", err.Error())
+		os.Exit(1)
+	}
+	opts = append(opts, WithLabels(labelValues), WithAnnotations(annotationValues))
+	opts = append(opts, WithKindFilter(*includeKinds, *excludeKinds), WithNamespaceFilter(*namespaceFilter...))
+	if *labelSelector != "" {
+		sel, err := parseSelector(*labelSelector)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --selector: %s\r\n", err.Error())
+			os.Exit(1)
+		}
+		opts = append(opts, WithSelector(sel))
+	}
+	if *strict {
+		opts = append(opts, WithStrictYAML())
+	}
+	if *skipGenerated {
+		opts = append(opts, WithSkipGenerated())
+	}
+	if *decodeSecrets {
+		opts = append(opts, WithDecodedSecrets())
+	}
+	if *structuredData {
+		opts = append(opts, WithStructuredData())
+	}
+	if *templateMetadata {
+		opts = append(opts, WithTemplateMetadata())
+	}
+	if *selectorLabels {
+		opts = append(opts, WithSelectorLabels())
+	}
+	for _, s := range *setExprs {
+		e, err := parseSetExpr(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --set-expr: %s\r\n", err.Error())
+			os.Exit(1)
+		}
+		opts = append(opts, WithSetExpressions(e))
+	}
+	if *crdStage != "" {
+		switch {
+		case diffMode, *outfile != "-", *moduleDir != "", *update != "", *mapOnly, *outputFormat != "hcl":
+			fmt.Fprintf(os.Stderr, "error: --crd-stage can't be used with tfk8s diff, --output, --module-dir, --update, --map-only or --format\r\n")
+			os.Exit(1)
+		}
+	}
+	if *crdStageWebhooks {
+		if *crdStage == "" {
+			fmt.Fprintf(os.Stderr, "error: --crd-stage-webhooks needs --crd-stage\r\n")
+			os.Exit(1)
+		}
+		opts = append(opts, WithCRDStageWebhooks())
+	}
+	if *moduleDir != "" && *outfile != "-" {
+		fmt.Fprintf(os.Stderr, "error: --module-dir and --output cannot be used together\r\n")
+		os.Exit(1)
+	}
+	if *moduleDir != "" && *mapOnly {
+		fmt.Fprintf(os.Stderr, "error: --module-dir and --map-only cannot be used together\r\n")
+		os.Exit(1)
+	}
+	if len(*moduleNamespaces) > 0 {
+		if *moduleDir == "" {
+			fmt.Fprintf(os.Stderr, "error: --module-namespace needs --module-dir\r\n")
+			os.Exit(1)
+		}
+		if *namespace != "" {
+			fmt.Fprintf(os.Stderr, "error: --module-namespace and --namespace cannot be used together\r\n")
+			os.Exit(1)
+		}
+		if *namespaceExpr == "" {
+			// the root modules set the namespace through this variable
+			*namespaceExpr = "var.namespace"
+		}
+		opts = append(opts, WithModuleNamespaces(*moduleNamespaces...))
+	}
+	if *moduleForEach {
+		if len(*moduleNamespaces) == 0 {
+			fmt.Fprintf(os.Stderr, "error: --module-for-each needs --module-namespace\r\n")
+			os.Exit(1)
+		}
+		opts = append(opts, WithModuleForEach())
+	}
+	opts = append(opts, WithProviderVersion(*providerVersion))
+	resourceBackend, err := parseBackend(*backendName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+		os.Exit(1)
+	}
+	if *mapOnly && resourceBackend.name != backendManifest.name {
+		fmt.Fprintf(os.Stderr, "error: --map-only can only be used with the manifest backend\r\n")
+		os.Exit(1)
+	}
+	opts = append(opts, WithBackend(resourceBackend))
+	lang, err := parseFormat(*outputFormat)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
 		os.Exit(1)
 	}
+	if lang != nil {
+		switch {
+		case *mapOnly, *moduleDir != "", *imports, *providerAlias != "", *kubeconfigPath != "",
+			*verify, resourceBackend.name != backendManifest.name:
+			fmt.Fprintf(os.Stderr, "error: --format %s can't be used with --map-only, --module-dir, --import, --provider, --provider-from-kubeconfig, --verify or --backend\r\n", lang.name)
+			os.Exit(1)
+		}
+		opts = append(opts, WithLanguage(*lang))
+	}
+	targetTool, err := parseTarget(*targetVersion)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: --target: %s\r\n", err.Error())
+		os.Exit(1)
+	}
+	opts = append(opts, WithTarget(targetTool))
+	if *imports {
+		opts = append(opts, WithImportBlocks())
+	}
+	if *prune {
+		opts = append(opts, WithPrune())
+	}
+	if *manifestDecode {
+		switch {
+		case *mapOnly, lang != nil, resourceBackend.name != backendManifest.name:
+			fmt.Fprintf(os.Stderr, "error: --manifest-decode can only be used with the manifest backend, and not with --map-only or --format\r\n")
+			os.Exit(1)
+		}
+		opts = append(opts, WithManifestDecode())
+	}
+	if *verify {
+		opts = append(opts, WithVerify())
+	}
+	if *kubeconfigPath != "" {
+		if *providerAlias != "" {
+			fmt.Fprintf(os.Stderr, "error: --provider and --provider-from-kubeconfig cannot be used together\r\n")
+			os.Exit(1)
+		}
+		providers, err := kubeconfigProviders(*kubeconfigPath, *kubeContexts, *inlineCluster)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+			os.Exit(1)
+		}
+		opts = append(opts, WithKubeconfigProviders(providers...))
+	} else if len(*kubeContexts) > 0 || *inlineCluster {
+		fmt.Fprintf(os.Stderr, "error: --kube-context and --inline-cluster need --provider-from-kubeconfig\r\n")
+		os.Exit(1)
+	}
+	if *namespace != "" && *namespaceExpr != "" {
+		fmt.Fprintf(os.Stderr, "error: --namespace and --namespace-expr cannot be used together\r\n")
+		os.Exit(1)
+	} else if *namespace != "" {
+		opts = append(opts, WithNamespace(*namespace))
+	} else if *namespaceExpr != "" {
+		opts = append(opts, WithNamespaceExpression(*namespaceExpr))
+	}
+
+	o := newOptions(*providerAlias, *stripServerSide, *mapOnly, *stripKeyQuotes, opts)
+	if diffMode {
+		file, err := openInput(*infile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+			os.Exit(1)
+		}
+		defer file.Close()
+		resources, err := convertManifests(file, o)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+			os.Exit(1)
+		}
+		existing, err := readConfiguration(*against, o)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+			os.Exit(1)
+		}
+		diffs := diffResources(resources, existing, o)
+		fmt.Print(formatDiff(diffs))
+		if len(diffs) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	run := func() error {
+		file, err := openInput(*infile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		resources, err := convertManifests(file, o)
+		if err != nil {
+			return err
+		}
 
-	if *outfile == "-" {
-		fmt.Print(hcl)
-	} else {
-		err := os.WriteFile(*outfile, []byte(hcl), 0644)
+		if *update != "" {
+			src, err := os.ReadFile(*update)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			updated, renamed, err := updateConfiguration(src, *update, resources, o)
+			if err != nil {
+				return err
+			}
+			if o.verify {
+				if err := verifyResources(string(updated), renamed, o); err != nil {
+					return err
+				}
+			}
+			if err := writeFile(*update, updated); err != nil {
+				return err
+			}
+			return writeFiles(filepath.Dir(*update), resources)
+		}
+
+		if *moduleDir != "" {
+			return writeModule(*moduleDir, resources, o)
+		}
+
+		if *crdStage != "" {
+			return writeStages(*crdStage, resources, o)
+		}
+
+		hcl, err := formatConfig(resources, o)
+		if err != nil {
+			return err
+		}
+
+		outdir := "."
+		if *outfile == "-" {
+			fmt.Print(hcl)
+		} else {
+			outdir = filepath.Dir(*outfile)
+			if err := writeFile(*outfile, []byte(hcl)); err != nil {
+				return err
+			}
+		}
+		return writeFiles(outdir, resources)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
+		if !*watch {
+			os.Exit(1)
+		}
+	}
+	if *watch {
+		fmt.Fprintf(os.Stderr, "watching %s for changes\r\n", *infile)
+		err := watchInput(*infile, run, os.Stderr, nil)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %s\r\n", err.Error())
 			os.Exit(1)
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
 )
 
 func TestYAMLToTerraformResourcesSingle(t *testing.T) {
@@ -96,10 +103,41 @@ resource "kubernetes_manifest" "configmap_test" {
   manifest = {
     "apiVersion" = "v1"
     "data" = {
-      "SCRIPT" = <<-EOT
-      echo "Hello, $${USER} your homedir is $${HOME}"
-      echo "\$${SHELL_ESCAPE$${TF_ESCAPE}}"
-      EOT
+      "SCRIPT" = "echo \"Hello, $${USER} your homedir is $${HOME}\"\necho \"\\$${SHELL_ESCAPE$${TF_ESCAPE}}\""
+    }
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+    }
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestYAMLToTerraformResourcesEscapeInterpolate(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  URL: https://${var.domain}/api`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false,
+		WithEscaping(terraform.EscapeInterpolate))
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	expected := `
+resource "kubernetes_manifest" "configmap_test" {
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      "URL" = "https://${var.domain}/api"
     }
     "kind" = "ConfigMap"
     "metadata" = {
@@ -421,3 +459,245 @@ resource "kubernetes_manifest" "configmap_test2" {
 
 	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
 }
+
+func TestYAMLToTerraformResourcesIndentedHeredoc(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  TEST: "  indented\n  text\n"`
+
+	r := strings.NewReader(yaml)
+	output, err := YAMLToTerraformResources(r, "", false, false, false)
+
+	if err != nil {
+		t.Fatal("Converting to HCL failed:", err)
+	}
+
+	// the heredoc can't be indented without changing its value
+	expected := `resource "kubernetes_manifest" "configmap_test" {
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      "TEST" = <<EOT
+  indented
+  text
+      EOT
+    }
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+    }
+  }
+}`
+
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(output))
+}
+
+func TestOpenInputDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.yaml":        "kind: B\n",
+		"a.yml":         "kind: A",
+		"nested/c.yaml": "kind: C\n",
+		"README.md":     "not a manifest\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := openInput(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "kind: A\n---\nkind: B\n\n---\nkind: C\n", string(b))
+}
+
+func TestWriteFileUnchanged(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.tf")
+	if err := writeFile(filename, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filename, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFile(filename, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, old, info.ModTime(), "a file with the same content was rewritten")
+
+	if err := writeFile(filename, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "b", string(b))
+}
+
+func TestSkipGenerated(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+    namespace: prod
+- apiVersion: apps/v1
+  kind: ReplicaSet
+  metadata:
+    name: web-5d8f7
+    namespace: prod
+    ownerReferences:
+    - apiVersion: apps/v1
+      kind: Deployment
+      name: web
+      controller: true
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: web-5d8f7-x2x9k
+    namespace: prod
+    ownerReferences:
+    - apiVersion: apps/v1
+      kind: ReplicaSet
+      name: web-5d8f7
+      controller: true
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+    namespace: prod
+    ownerReferences:
+    - apiVersion: example.com/v1
+      kind: App
+      name: web
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: kube-root-ca.crt
+    namespace: prod
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: default
+    namespace: prod
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: web
+    namespace: prod
+- apiVersion: v1
+  kind: Secret
+  type: kubernetes.io/service-account-token
+  metadata:
+    name: web-token-x7k2q
+    namespace: prod
+    annotations:
+      kubernetes.io/service-account.name: web
+      kubernetes.io/service-account.uid: 0b8a1f3e-4a39-4e0e-9d4c-2f2e6c3b9a51
+- apiVersion: v1
+  kind: Secret
+  type: kubernetes.io/service-account-token
+  metadata:
+    name: web-token
+    namespace: prod
+    annotations:
+      kubernetes.io/service-account.name: web
+      kubernetes.io/service-account.uid: 0b8a1f3e-4a39-4e0e-9d4c-2f2e6c3b9a51
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: kubernetes
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: kubernetes
+    namespace: prod
+- apiVersion: v1
+  kind: Endpoints
+  metadata:
+    name: web
+    namespace: prod
+- apiVersion: discovery.k8s.io/v1
+  kind: EndpointSlice
+  metadata:
+    name: web-abcde
+    namespace: prod
+- apiVersion: events.k8s.io/v1
+  kind: Event
+  metadata:
+    name: web.17a
+    namespace: prod
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: kube-system
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: prod
+`
+
+	o := newOptions("", true, false, false, []Option{WithSkipGenerated()})
+	resources, err := convertManifests(strings.NewReader(yaml), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, r := range resources {
+		names = append(names, r.name)
+	}
+
+	// objects with owners that aren't controllers are kept, and so are
+	// token Secrets that were named by a user rather than generated
+	assert.Equal(t, []string{
+		"deployment_prod_web",
+		"configmap_prod_settings",
+		"serviceaccount_prod_web",
+		"secret_prod_web_token",
+		"service_prod_kubernetes",
+		"namespace_prod",
+	}, names)
+}
+
+func TestCheckUTF8(t *testing.T) {
+	doc := cty.ObjectVal(map[string]cty.Value{
+		"data": cty.ObjectVal(map[string]cty.Value{
+			"ok":  cty.StringVal("hello"),
+			"bad": cty.StringVal("bad \xff utf8"),
+		}),
+	})
+	assert.EqualError(t, checkUTF8(doc, nil), "the string at data.bad is not valid UTF-8, which Terraform can't hold")
+
+	doc = cty.ObjectVal(map[string]cty.Value{
+		"items": cty.TupleVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+			"bad \xfe": cty.StringVal("value"),
+		})}),
+	})
+	assert.Error(t, checkUTF8(doc, nil))
+
+	assert.NoError(t, checkUTF8(cty.ObjectVal(map[string]cty.Value{"ok": cty.StringVal("ünïcode 🎉")}), nil))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	cty "github.com/zclconf/go-cty/cty"
+)
+
+// updateConfiguration rewrites the attribute that holds the manifest in
+// the resource blocks of src that match the resources, and appends blocks
+// for the resources that don't match any. Everything else in the file is
+// left as it is. Matched blocks keep their names, so the resources are
+// returned with the names they have in the updated file.
+func updateConfiguration(src []byte, filename string, resources []resource, o *options) ([]byte, []resource, error) {
+	existing, err := parseConfiguration(src, filename, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("%s", diags.Error())
+	}
+
+	blocks := map[string]*hclwrite.Block{}
+	for _, b := range f.Body().Blocks() {
+		labels := b.Labels()
+		if b.Type() == "resource" && len(labels) == 2 && labels[0] == o.backend.resourceType {
+			blocks[labels[1]] = b
+		}
+	}
+
+	matched, _ := matchResources(resources, existing)
+	used := map[string]bool{}
+	for i := range resources {
+		if matched[i] != -1 {
+			used[existing[matched[i]].name] = true
+		}
+	}
+
+	updated := make([]resource, len(resources))
+	for i, r := range resources {
+		name := r.name
+		if matched[i] != -1 {
+			name = existing[matched[i]].name
+		} else if _, ok := blocks[name]; !ok || used[name] {
+			// a new document
+			formatted, err := yamlToHCL(r, o)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error converting YAML to HCL: %s", err)
+			}
+			parsed, diags := hclwrite.ParseConfig([]byte(formatted), r.name, hcl.InitialPos)
+			if diags.HasErrors() {
+				return nil, nil, fmt.Errorf("%s: %s", r.name, diags.Error())
+			}
+			for _, b := range parsed.Body().Blocks() {
+				if len(f.Bytes()) > 0 {
+					f.Body().AppendNewline()
+				}
+				f.Body().AppendBlock(b)
+			}
+			updated[i] = r
+			continue
+		}
+		used[name] = true
+
+		tokens, err := attributeTokens(o.backend.body(r, o), o.backend.attribute)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", r.name, err)
+		}
+		blocks[name].Body().SetAttributeRaw(o.backend.attribute, tokens)
+		r.name = name
+		updated[i] = r
+	}
+
+	for _, e := range existing {
+		if used[e.name] {
+			continue
+		}
+		address := fmt.Sprintf("%s.%s", o.backend.resourceType, e.name)
+		switch {
+		case !o.prune:
+			o.warnf("%s is not in the manifests, leaving it as it is", address)
+		case !o.target.supports(featureRemovedBlocks):
+			o.warnf("%s does not support %s, leaving %s as it is, run %s state rm '%s' and delete it to stop managing the object",
+				o.target, featureRemovedBlocks.name, address, o.target.command(), address)
+		default:
+			forgetBlock(blocks[e.name], e.name, o)
+		}
+	}
+	return f.Bytes(), updated, nil
+}
+
+// forgetBlock turns a resource block into a removed block, which makes
+// Terraform forget the resource without deleting the object
+func forgetBlock(b *hclwrite.Block, name string, o *options) {
+	b.SetType("removed")
+	b.SetLabels(nil)
+	b.Body().Clear()
+	b.Body().AppendNewline()
+	b.Body().SetAttributeTraversal("from", hcl.Traversal{
+		hcl.TraverseRoot{Name: o.backend.resourceType},
+		hcl.TraverseAttr{Name: name},
+	})
+	if o.target.tool == toolTerraform {
+		// OpenTofu never destroys the objects of removed resources,
+		// Terraform does unless it is told not to
+		b.Body().AppendNewline()
+		lifecycle := b.Body().AppendNewBlock("lifecycle", nil)
+		lifecycle.Body().SetAttributeValue("destroy", cty.False)
+	}
+}
+
+// attributeTokens returns the tokens of the value of an attribute
+// in the body of a resource written by the backend
+func attributeTokens(body, name string) (hclwrite.Tokens, error) {
+	f, diags := hclwrite.ParseConfig([]byte(body), name, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s", diags.Error())
+	}
+	attr := f.Body().GetAttribute(name)
+	if attr == nil {
+		return nil, fmt.Errorf("the backend did not write %s", name)
+	}
+	return attr.Expr().BuildTokens(nil), nil
+}
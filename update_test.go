@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const updateYAML = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  mode: "0644"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: new
+`
+
+const updateConfig = `# written by tfk8s
+resource "kubernetes_manifest" "settings" {
+  # renamed by hand
+  manifest = {
+    "apiVersion" = "v1"
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+    }
+  }
+
+  wait {
+    fields = {
+      "status.phase" = "Active"
+    }
+  }
+
+  depends_on = [kubernetes_manifest.orphan] # keep this
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+
+resource "kubernetes_manifest" "orphan" {
+  manifest = {}
+}
+
+resource "random_id" "other" {
+  byte_length = 8
+}
+`
+
+func TestUpdateConfiguration(t *testing.T) {
+	warnings := bytes.Buffer{}
+	o := newOptions("", false, false, false, []Option{WithWarnings(&warnings)})
+	resources, err := convertManifests(strings.NewReader(updateYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, updated, err := updateConfiguration([]byte(updateConfig), "main.tf", resources, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# written by tfk8s
+resource "kubernetes_manifest" "settings" {
+  # renamed by hand
+  manifest = {
+    "apiVersion" = "v1"
+    "data" = {
+      "mode" = "0644"
+    }
+    "kind" = "ConfigMap"
+    "metadata" = {
+      "name" = "test"
+    }
+  }
+
+  wait {
+    fields = {
+      "status.phase" = "Active"
+    }
+  }
+
+  depends_on = [kubernetes_manifest.orphan] # keep this
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+
+resource "kubernetes_manifest" "orphan" {
+  manifest = {}
+}
+
+resource "random_id" "other" {
+  byte_length = 8
+}
+
+resource "kubernetes_manifest" "service_new" {
+  manifest = {
+    "apiVersion" = "v1"
+    "kind"       = "Service"
+    "metadata" = {
+      "name" = "new"
+    }
+  }
+}
+`
+
+	assert.Equal(t, expected, string(output))
+	assert.Equal(t, "settings", updated[0].name)
+	assert.Equal(t, "service_new", updated[1].name)
+	assert.Equal(t, "warning: kubernetes_manifest.orphan is not in the manifests, leaving it as it is\n", warnings.String())
+	assert.NoError(t, verifyResources(string(output), updated, o))
+}
+
+func TestUpdateConfigurationEmpty(t *testing.T) {
+	o := newOptions("", false, false, false, []Option{WithBackend(backendKubectl)})
+	resources, err := convertManifests(strings.NewReader(updateYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, _, err := updateConfiguration(nil, "main.tf", resources, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := formatResources(resources, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, expected, string(output))
+
+	// updating again with the same manifests changes nothing
+	again, _, err := updateConfiguration(output, "main.tf", resources, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(output), string(again))
+}
+
+func TestUpdateConfigurationPrune(t *testing.T) {
+	config := `resource "kubernetes_manifest" "configmap_test" {
+  manifest = {}
+}
+
+# no longer in the manifests
+resource "kubernetes_manifest" "orphan" {
+  manifest = {}
+}
+`
+	tests := []struct {
+		target   string
+		expected string
+		warning  string
+	}{
+		{
+			target: "terraform@1.7",
+			expected: `# no longer in the manifests
+removed {
+  from = kubernetes_manifest.orphan
+
+  lifecycle {
+    destroy = false
+  }
+}
+`,
+		},
+		{
+			target: "opentofu@1.7",
+			expected: `# no longer in the manifests
+removed {
+  from = kubernetes_manifest.orphan
+}
+`,
+		},
+		{
+			target: "terraform@1.5",
+			expected: `# no longer in the manifests
+resource "kubernetes_manifest" "orphan" {
+  manifest = {}
+}
+`,
+			warning: "warning: terraform@1.5 does not support removed blocks, leaving kubernetes_manifest.orphan as it is, " +
+				"run terraform state rm 'kubernetes_manifest.orphan' and delete it to stop managing the object\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			target, err := parseTarget(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings := bytes.Buffer{}
+			o := newOptions("", false, false, false, []Option{WithPrune(), WithTarget(target), WithWarnings(&warnings)})
+			resources, err := convertManifests(strings.NewReader(updateYAML), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, _, err := updateConfiguration([]byte(config), "main.tf", resources, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Contains(t, string(output), "\n\n"+tt.expected+"\n"+`resource "kubernetes_manifest" "service_new"`)
+			assert.Equal(t, tt.warning, warnings.String())
+		})
+	}
+}
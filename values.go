@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	cty "github.com/zclconf/go-cty/cty"
+)
+
+// getAttr returns the value at the path of nested object attributes
+// and whether it exists
+func getAttr(v cty.Value, path ...string) (cty.Value, bool) {
+	for _, attr := range path {
+		if v.IsNull() || !v.Type().IsObjectType() || !v.Type().HasAttribute(attr) {
+			return cty.NilVal, false
+		}
+		v = v.GetAttr(attr)
+	}
+	return v, true
+}
+
+// getString returns the string at the path of nested object attributes,
+// or an empty string if there is no string there
+func getString(v cty.Value, path ...string) string {
+	s, ok := getAttr(v, path...)
+	if !ok || s.Type() != cty.String || s.IsNull() {
+		return ""
+	}
+	return s.AsString()
+}
+
+// valueMap is like AsValueMap but always returns a map that can be written to
+func valueMap(v cty.Value) map[string]cty.Value {
+	if v.IsNull() || !v.Type().IsObjectType() {
+		return map[string]cty.Value{}
+	}
+	m := v.AsValueMap()
+	if m == nil {
+		m = map[string]cty.Value{}
+	}
+	return m
+}
+
+// updateAttr replaces the value at the path of nested object attributes
+// with the result of fn, which is passed cty.NilVal if the attribute does
+// not exist yet. Missing objects along the path are created when create is
+// true, otherwise the value is returned unchanged.
+func updateAttr(v cty.Value, create bool, fn func(cty.Value) cty.Value, path ...string) cty.Value {
+	if len(path) == 0 {
+		return fn(v)
+	}
+	if v == cty.NilVal || v.IsNull() || !v.Type().IsObjectType() {
+		if !create || (v != cty.NilVal && !v.IsNull()) {
+			return v
+		}
+		v = cty.EmptyObjectVal
+	}
+	m := valueMap(v)
+	child, ok := m[path[0]]
+	if !ok {
+		if !create && len(path) > 1 {
+			return v
+		}
+		child = cty.NilVal
+	}
+	updated := updateAttr(child, create, fn, path[1:]...)
+	if updated == cty.NilVal {
+		// fn left the attribute missing
+		return v
+	}
+	m[path[0]] = updated
+	return cty.ObjectVal(m)
+}
+
+// checkUTF8 returns an error for the first key or string that is not valid
+// UTF-8, since Terraform strings can't hold one
+func checkUTF8(v cty.Value, path []pathStep) error {
+	if v == cty.NilVal || v.IsNull() || !v.IsKnown() {
+		return nil
+	}
+	ty := v.Type()
+	switch {
+	case ty == cty.String:
+		if !utf8.ValidString(v.AsString()) {
+			return fmt.Errorf("the string at %s is not valid UTF-8, which Terraform can't hold", verifyPath(path))
+		}
+	case ty.IsObjectType() || ty.IsMapType():
+		for k, ev := range v.AsValueMap() {
+			p := append(path[:len(path):len(path)], pathStep{attr: k})
+			if !utf8.ValidString(k) {
+				return fmt.Errorf("the key at %s is not valid UTF-8, which Terraform can't hold", verifyPath(p))
+			}
+			if err := checkUTF8(ev, p); err != nil {
+				return err
+			}
+		}
+	case ty.IsTupleType() || ty.IsListType():
+		for i, ev := range v.AsValueSlice() {
+			if err := checkUTF8(ev, append(path[:len(path):len(path)], pathStep{index: i})); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
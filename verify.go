@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	cty "github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+// base64DecodeFunc is Terraform's base64decode function, which
+// fails when the decoded bytes are not valid UTF-8
+var base64DecodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		b, err := base64.StdEncoding.DecodeString(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.String), err
+		}
+		if !utf8.Valid(b) {
+			return cty.UnknownVal(cty.String), fmt.Errorf("the result of decoding the provided string is not valid UTF-8")
+		}
+		return cty.StringVal(string(b)), nil
+	},
+})
+
+// base64EncodeFunc is Terraform's base64encode function
+var base64EncodeFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.StringVal(base64.StdEncoding.EncodeToString([]byte(args[0].AsString()))), nil
+	},
+})
+
+// verifyContext has the functions the formatter uses to write literal values
+var verifyContext = &hcl.EvalContext{
+	Functions: map[string]function.Function{
+		"chomp":        stdlib.ChompFunc,
+		"base64decode": base64DecodeFunc,
+	},
+}
+
+// encodeContext also has the functions tfk8s replaces values with, so
+// that the calls can be checked against the values they replaced
+var encodeContext = &hcl.EvalContext{
+	Functions: map[string]function.Function{
+		"chomp":        stdlib.ChompFunc,
+		"base64decode": base64DecodeFunc,
+		"base64encode": base64EncodeFunc,
+		"jsonencode":   stdlib.JSONEncodeFunc,
+		"yamlencode":   ctyyaml.YAMLEncodeFunc,
+	},
+}
+
+// verifyResources parses the HCL written for the resources and checks
+// that each manifest reads back as the document it was written from,
+// returning an error with the path of the first difference
+func verifyResources(config string, resources []resource, o *options) error {
+	if o.mapOnly {
+		// the maps are separate expressions rather than a config file
+		for _, r := range resources {
+			s, err := yamlToHCL(r, o)
+			if err != nil {
+				return err
+			}
+			v := verifier{src: []byte(s), o: o, original: r.original}
+			expr, diags := hclsyntax.ParseExpression(v.src, r.name, hcl.InitialPos)
+			if diags.HasErrors() {
+				return fmt.Errorf("verify: %s: %s", r.name, diags.Error())
+			}
+			if err := v.expression(expr, r.doc, nil); err != nil {
+				return fmt.Errorf("verify: %s: %s", r.name, err)
+			}
+		}
+		return nil
+	}
+
+	v := verifier{src: []byte(config), o: o}
+	f, diags := hclsyntax.ParseConfig(v.src, "main.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("verify: %s", diags.Error())
+	}
+
+	blocks := map[string]*hclsyntax.Block{}
+	for _, b := range f.Body.(*hclsyntax.Body).Blocks {
+		if b.Type == "resource" && len(b.Labels) == 2 && b.Labels[0] == o.backend.resourceType {
+			blocks[b.Labels[1]] = b
+		}
+	}
+
+	for _, r := range resources {
+		b, ok := blocks[r.name]
+		if !ok {
+			return fmt.Errorf("verify: resource %s.%s is missing from the output", o.backend.resourceType, r.name)
+		}
+		attr, ok := b.Body.Attributes[o.backend.attribute]
+		if !ok {
+			return fmt.Errorf("verify: %s: %s is missing from the output", r.name, o.backend.attribute)
+		}
+		v.original = r.original
+		if err := v.attribute(attr.Expr, r.doc); err != nil {
+			return fmt.Errorf("verify: %s: %s", r.name, err)
+		}
+	}
+	return nil
+}
+
+// verifier compares the syntax of the output with the values it was written from
+type verifier struct {
+	src []byte
+	o   *options
+
+	// original is the document before values were replaced with function
+	// calls, or cty.NilVal to only compare the syntax of the calls
+	original cty.Value
+}
+
+// attribute checks the attribute of a resource that holds the manifest
+func (v verifier) attribute(expr hclsyntax.Expression, doc cty.Value) error {
+	o := v.o
+	call, isCall := expr.(*hclsyntax.FunctionCallExpr)
+	switch {
+	case isCall && contains(decodeFunctions, call.Name) && len(call.Args) == 1:
+		// the YAML of --manifest-decode
+		return v.yamlString(call.Args[0], doc)
+	case !o.backend.yaml:
+		return v.expression(expr, doc, nil)
+	case isCall:
+		return v.expression(expr, terraform.FunctionCallVal("yamlencode", doc), nil)
+	}
+	return v.yamlString(expr, doc)
+}
+
+// yamlString checks a string holding the document as YAML
+func (v verifier) yamlString(expr hclsyntax.Expression, doc cty.Value) error {
+	o := v.o
+	if o.escaping == terraform.EscapeInterpolate && len(expr.Variables()) > 0 {
+		// the YAML can only be read once Terraform has interpolated it
+		return nil
+	}
+
+	body, diags := expr.Value(verifyContext)
+	if diags.HasErrors() {
+		return fmt.Errorf("%s: %s", o.backend.attribute, diags.Error())
+	}
+	if body.Type() != cty.String || body.IsNull() {
+		return fmt.Errorf("%s: expected a YAML string", o.backend.attribute)
+	}
+	manifests, err := readManifests(strings.NewReader(body.AsString()))
+	if err != nil {
+		return fmt.Errorf("%s: %s", o.backend.attribute, err)
+	}
+	if len(manifests) != 1 {
+		return fmt.Errorf("%s: expected one YAML document but found %d", o.backend.attribute, len(manifests))
+	}
+	return compareValues(doc, manifests[0], nil)
+}
+
+// expression checks that an expression reads back as the value it was
+// written from. Function calls that replaced a string of the original
+// document are evaluated and compared with that string. Terraform
+// expressions, and calls that use them, are compared with the syntax
+// instead, since they can't be evaluated.
+func (v verifier) expression(expr hclsyntax.Expression, want cty.Value, path []pathStep) error {
+	ty := want.Type()
+	switch {
+	case terraform.IsExpression(want):
+		got := strings.TrimSpace(string(expr.Range().SliceBytes(v.src)))
+		if got != terraform.ExpressionString(want) {
+			return fmt.Errorf("%s: expected the expression %s but the output has %s",
+				verifyPath(path), terraform.ExpressionString(want), got)
+		}
+		return nil
+	case terraform.IsFunctionCall(want):
+		name, args := terraform.FunctionCallArgs(want)
+		call, ok := expr.(*hclsyntax.FunctionCallExpr)
+		if !ok || call.Name != name || len(call.Args) != len(args) {
+			return fmt.Errorf("%s: expected a call to %s() with %d arguments", verifyPath(path), name, len(args))
+		}
+		original, ok := valueAt(v.original, path)
+		if ok && original.Type() == cty.String && !original.IsNull() && !hasExpression(want) &&
+			!(v.o.escaping == terraform.EscapeInterpolate && len(expr.Variables()) > 0) {
+			got, diags := expr.Value(encodeContext)
+			if diags.HasErrors() {
+				return fmt.Errorf("%s: %s", verifyPath(path), diags.Error())
+			}
+			if err := compareValues(original, got, path); err != nil {
+				return fmt.Errorf("%s() does not produce the input: %s", name, err)
+			}
+			return nil
+		}
+		// the arguments don't replace a value of the original document
+		inner := v
+		inner.original = cty.NilVal
+		for i, arg := range args {
+			if err := inner.expression(call.Args[i], arg, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	case !want.IsNull() && (ty.IsObjectType() || ty.IsMapType()):
+		obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+		if !ok {
+			return fmt.Errorf("%s: expected an object", verifyPath(path))
+		}
+		want := valueMap(want)
+		seen := map[string]bool{}
+		for _, item := range obj.Items {
+			k, diags := item.KeyExpr.Value(verifyContext)
+			if diags.HasErrors() {
+				return fmt.Errorf("%s: %s", verifyPath(path), diags.Error())
+			}
+			if k.Type() != cty.String || k.IsNull() {
+				return fmt.Errorf("%s: expected a string key", verifyPath(path))
+			}
+			key := k.AsString()
+			p := append(path[:len(path):len(path)], pathStep{attr: key})
+			if seen[key] {
+				return fmt.Errorf("%s: the key is written more than once", verifyPath(p))
+			}
+			seen[key] = true
+			value, ok := want[key]
+			if !ok {
+				return fmt.Errorf("%s: the key is not in the input", verifyPath(p))
+			}
+			if err := v.expression(item.ValueExpr, value, p); err != nil {
+				return err
+			}
+		}
+		for key := range want {
+			if !seen[key] {
+				p := append(path[:len(path):len(path)], pathStep{attr: key})
+				return fmt.Errorf("%s: the key is missing from the output", verifyPath(p))
+			}
+		}
+		return nil
+	case !want.IsNull() && (ty.IsTupleType() || ty.IsListType()):
+		tuple, ok := expr.(*hclsyntax.TupleConsExpr)
+		if !ok {
+			return fmt.Errorf("%s: expected a list", verifyPath(path))
+		}
+		elems := want.AsValueSlice()
+		if len(tuple.Exprs) != len(elems) {
+			return fmt.Errorf("%s: expected %d elements but the output has %d", verifyPath(path), len(elems), len(tuple.Exprs))
+		}
+		for i, el := range elems {
+			p := append(path[:len(path):len(path)], pathStep{index: i})
+			if err := v.expression(tuple.Exprs[i], el, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if v.o.escaping == terraform.EscapeInterpolate && len(expr.Variables()) > 0 {
+		// strings that interpolate expressions can't be read back
+		return nil
+	}
+	got, diags := expr.Value(verifyContext)
+	if diags.HasErrors() {
+		return fmt.Errorf("%s: %s", verifyPath(path), diags.Error())
+	}
+	return compareValues(want, got, path)
+}
+
+// compareValues checks that two values without expressions are the same
+func compareValues(want, got cty.Value, path []pathStep) error {
+	wantTy, gotTy := want.Type(), got.Type()
+	switch {
+	case want.IsNull() || got.IsNull():
+		if want.IsNull() && got.IsNull() {
+			return nil
+		}
+	case (wantTy.IsObjectType() || wantTy.IsMapType()) && (gotTy.IsObjectType() || gotTy.IsMapType()):
+		wm, gm := valueMap(want), valueMap(got)
+		for key, v := range wm {
+			p := append(path[:len(path):len(path)], pathStep{attr: key})
+			g, ok := gm[key]
+			if !ok {
+				return fmt.Errorf("%s: the key is missing from the output", verifyPath(p))
+			}
+			if err := compareValues(v, g, p); err != nil {
+				return err
+			}
+		}
+		for key := range gm {
+			if _, ok := wm[key]; !ok {
+				p := append(path[:len(path):len(path)], pathStep{attr: key})
+				return fmt.Errorf("%s: the key is not in the input", verifyPath(p))
+			}
+		}
+		return nil
+	case (wantTy.IsTupleType() || wantTy.IsListType()) && (gotTy.IsTupleType() || gotTy.IsListType()):
+		we, ge := want.AsValueSlice(), got.AsValueSlice()
+		if len(we) != len(ge) {
+			return fmt.Errorf("%s: expected %d elements but the output has %d", verifyPath(path), len(we), len(ge))
+		}
+		for i := range we {
+			p := append(path[:len(path):len(path)], pathStep{index: i})
+			if err := compareValues(we[i], ge[i], p); err != nil {
+				return err
+			}
+		}
+		return nil
+	case wantTy.IsPrimitiveType() && wantTy.Equals(gotTy):
+		if want.Equals(got).True() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: expected %s but the output has %s", verifyPath(path), verifyString(want), verifyString(got))
+}
+
+// verifyPath formats the path of a difference
+func verifyPath(path []pathStep) string {
+	if len(path) == 0 {
+		return "manifest"
+	}
+	return formatPath(path)
+}
+
+// verifyString formats a value for an error message
+func verifyString(v cty.Value) string {
+	if !v.IsWhollyKnown() {
+		return "an unknown value"
+	}
+	b, err := ctyjson.Marshal(v, v.Type())
+	if err != nil {
+		return v.GoString()
+	}
+	return string(b)
+}
+
+// valueAt returns the value at a path of attributes and indexes
+func valueAt(v cty.Value, path []pathStep) (cty.Value, bool) {
+	for _, step := range path {
+		if v == cty.NilVal || v.IsNull() || !v.IsKnown() {
+			return cty.NilVal, false
+		}
+		ty := v.Type()
+		switch {
+		case step.attr != "" && (ty.IsObjectType() || ty.IsMapType()):
+			m := v.AsValueMap()
+			next, ok := m[step.attr]
+			if !ok {
+				return cty.NilVal, false
+			}
+			v = next
+		case step.attr == "" && (ty.IsTupleType() || ty.IsListType()):
+			elems := v.AsValueSlice()
+			if step.index < 0 || step.index >= len(elems) {
+				return cty.NilVal, false
+			}
+			v = elems[step.index]
+		default:
+			return cty.NilVal, false
+		}
+	}
+	return v, v != cty.NilVal
+}
+
+// hasExpression returns true if the value holds a Terraform expression,
+// which only Terraform can evaluate
+func hasExpression(v cty.Value) bool {
+	switch {
+	case terraform.IsExpression(v):
+		return true
+	case terraform.IsFunctionCall(v):
+		_, args := terraform.FunctionCallArgs(v)
+		for _, arg := range args {
+			if hasExpression(arg) {
+				return true
+			}
+		}
+		return false
+	case v.IsNull() || !v.IsKnown():
+		return false
+	}
+	ty := v.Type()
+	if ty.IsObjectType() || ty.IsMapType() || ty.IsTupleType() || ty.IsListType() {
+		for it := v.ElementIterator(); it.Next(); {
+			if _, ev := it.Element(); hasExpression(ev) {
+				return true
+			}
+		}
+	}
+	return false
+}
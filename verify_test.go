@@ -0,0 +1,211 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	cty "github.com/zclconf/go-cty/cty"
+
+	"github.com/jrhouston/tfk8s/contrib/hashicorp/terraform"
+)
+
+const verifyYAML = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: web
+  annotations:
+    example.com/quoted-key: "yes"
+data:
+  script: |
+    #!/bin/sh
+    echo ${HOME} 100%
+  unicode: "héllo ✓"
+  enabled: "on"
+  mode: "0644"
+  config.json: '{"a": 1, "b": [true, null]}'
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  replicas: 12345678901234567890
+  template:
+    spec:
+      containers:
+      - name: test
+        args: ["--port", "8080"]
+        resources: {}
+`
+
+func TestVerify(t *testing.T) {
+	data, err := parseSetExpr("data.mode=var.mode")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		mapOnly        bool
+		stripKeyQuotes bool
+		opts           []Option
+	}{
+		{name: "manifest"},
+		{name: "strip key quotes", stripKeyQuotes: true},
+		{name: "map only", mapOnly: true},
+		{name: "heredocs", opts: []Option{WithHeredoc(terraform.HeredocAlways)}},
+		{name: "interpolate", opts: []Option{WithEscaping(terraform.EscapeInterpolate)}},
+		{name: "structured data", opts: []Option{WithStructuredData(), WithSetExpressions(data)}},
+		{name: "kubectl", opts: []Option{WithBackend(backendKubectl)}},
+		{name: "kubectl expressions", opts: []Option{WithBackend(backendKubectl), WithSetExpressions(data)}},
+		{name: "k8s", opts: []Option{WithBackend(backendK8s)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := strings.NewReader(verifyYAML)
+			opts := append(tt.opts, WithVerify())
+			_, err := YAMLToTerraformResources(r, "", false, tt.mapOnly, tt.stripKeyQuotes, opts...)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifyDifference(t *testing.T) {
+	o := newOptions("", false, false, false, nil)
+	resources, err := convertManifests(strings.NewReader(verifyYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := formatResources(resources, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{
+			name:     "changed value",
+			old:      `"mode" = "0644"`,
+			new:      `"mode" = "644"`,
+			expected: `verify: configmap_web_test: data.mode: expected "0644" but the output has "644"`,
+		},
+		{
+			name:     "changed type",
+			old:      `"replicas" = 12345678901234567890`,
+			new:      `"replicas" = "12345678901234567890"`,
+			expected: `verify: deployment_test: spec.replicas: expected 12345678901234567890 but the output has "12345678901234567890"`,
+		},
+		{
+			name:     "missing key",
+			old:      `"unicode" = "héllo ✓"`,
+			new:      ``,
+			expected: `verify: configmap_web_test: data.unicode: the key is missing from the output`,
+		},
+		{
+			name:     "extra element",
+			old:      `"8080",`,
+			new:      `"8080", "8081",`,
+			expected: `verify: deployment_test: spec.template.spec.containers[0].args: expected 2 elements but the output has 3`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(config, tt.old) {
+				t.Fatalf("the output does not contain %s:\n%s", tt.old, config)
+			}
+			err := verifyResources(strings.Replace(config, tt.old, tt.new, 1), resources, o)
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestVerifyYAMLDifference(t *testing.T) {
+	o := newOptions("", false, false, false, []Option{WithBackend(backendKubectl)})
+	resources, err := convertManifests(strings.NewReader(verifyYAML), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := formatResources(resources, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifyResources(strings.Replace(config, `enabled: "on"`, `enabled: on`, 1), resources, o)
+	if assert.Error(t, err) {
+		assert.Equal(t, `verify: configmap_web_test: data.enabled: expected "on" but the output has true`, err.Error())
+	}
+}
+
+func TestVerifyBase64DecodeInvalidUTF8(t *testing.T) {
+	v, err := base64DecodeFunc.Call([]cty.Value{cty.StringVal("aGVsbG8=")})
+	assert.NoError(t, err)
+	assert.Equal(t, cty.StringVal("hello"), v)
+
+	// Terraform's base64decode fails on bytes that aren't UTF-8
+	_, err = base64DecodeFunc.Call([]cty.Value{cty.StringVal("/w==")})
+	assert.Error(t, err)
+}
+
+func TestVerifyFunctionCalls(t *testing.T) {
+	yaml := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+data:
+  config.yaml: Y291bnRyeTogbm8K
+`
+	tests := []struct {
+		name      string
+		transform func(cty.Value) cty.Value
+		expected  string
+	}{
+		{
+			name: "decoded",
+		},
+		{
+			// YAML 1.1 reads "no" as false, which yamlencode() writes back as false
+			name: "lossy",
+			transform: func(cty.Value) cty.Value {
+				return terraform.FunctionCallVal("base64encode", terraform.FunctionCallVal("yamlencode",
+					cty.ObjectVal(map[string]cty.Value{"country": cty.False})))
+			},
+			expected: `verify: secret_test: base64encode() does not produce the input: data["config.yaml"]: ` +
+				`expected "Y291bnRyeTogbm8K" but the output has "ImNvdW50cnkiOiBmYWxzZQo="`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOptions("", false, false, false, []Option{WithDecodedSecrets()})
+			resources, err := convertManifests(strings.NewReader(yaml), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.transform != nil {
+				resources[0].doc = updateAttr(resources[0].doc, false, tt.transform, "data", "config.yaml")
+			}
+			config, err := formatResources(resources, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = verifyResources(config, resources, o)
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Equal(t, tt.expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long to wait for more changes to the input
+// before converting it again, since editors often write a file in steps
+const watchDebounce = 100 * time.Millisecond
+
+// watchInput calls run each time the input file, or a YAML file in the
+// input directory, changes until stop is closed. Errors are written to
+// stderr rather than returned so that a broken manifest doesn't end the watch.
+func watchInput(path string, run func() error, stderr io.Writer, stop <-chan struct{}) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	// editors replace files rather than writing to them, which ends a
+	// watch on the file itself, so the directory that holds it is watched
+	relevant := func(name string) bool {
+		return filepath.Clean(name) == filepath.Clean(path)
+	}
+	if info.IsDir() {
+		relevant = func(name string) bool {
+			ext := filepath.Ext(name)
+			return ext == ".yaml" || ext == ".yml"
+		}
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			return w.Add(p)
+		})
+	} else {
+		err = w.Add(filepath.Dir(path))
+	}
+	if err != nil {
+		return err
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if info.IsDir() && event.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					if err := w.Add(event.Name); err != nil {
+						fmt.Fprintf(stderr, "error: %s\r\n", err.Error())
+					}
+					continue
+				}
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 && relevant(event.Name) {
+				debounce = time.After(watchDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(stderr, "error: %s\r\n", err.Error())
+		case <-debounce:
+			debounce = nil
+			if err := run(); err != nil {
+				fmt.Fprintf(stderr, "error: %s\r\n", err.Error())
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// watchRuns starts watching path and returns a channel that receives a
+// value each time the input is converted, and a function that stops the
+// watch so that stderr can be read
+func watchRuns(t *testing.T, path string, stderr *bytes.Buffer, err error) (chan struct{}, func()) {
+	runs := make(chan struct{}, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watchErr := watchInput(path, func() error {
+			runs <- struct{}{}
+			return err
+		}, stderr, stop)
+		assert.NoError(t, watchErr)
+	}()
+	once := sync.Once{}
+	stopWatch := func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+	}
+	t.Cleanup(stopWatch)
+
+	// give the watcher time to start
+	time.Sleep(50 * time.Millisecond)
+	return runs, stopWatch
+}
+
+// expectRuns checks how many times the input was converted
+func expectRuns(t *testing.T, runs chan struct{}, n int) {
+	time.Sleep(3 * watchDebounce)
+	assert.Equal(t, n, len(runs))
+	for len(runs) > 0 {
+		<-runs
+	}
+}
+
+func TestWatchInputDirectory(t *testing.T) {
+	dir := t.TempDir()
+	stderr := bytes.Buffer{}
+	runs, stop := watchRuns(t, dir, &stderr, nil)
+
+	// changes close together are converted once
+	for i := 0; i < 3; i++ {
+		err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("kind: A\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectRuns(t, runs, 1)
+
+	// files that aren't manifests are ignored
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectRuns(t, runs, 0)
+
+	// new directories are watched too
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "nested", "b.yml"), []byte("kind: B\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectRuns(t, runs, 1)
+	stop()
+	assert.Empty(t, stderr.String())
+}
+
+func TestWatchInputFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.yaml")
+	if err := os.WriteFile(path, []byte("kind: A\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stderr := bytes.Buffer{}
+	runs, stop := watchRuns(t, path, &stderr, errors.New("the manifest is broken"))
+
+	// other files next to the input are ignored
+	if err := os.WriteFile(filepath.Join(dir, "other.yaml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectRuns(t, runs, 0)
+
+	// replacing the file the way editors do is a change, and errors
+	// are reported without ending the watch
+	for i := 0; i < 2; i++ {
+		tmp := filepath.Join(dir, "manifest.yaml~")
+		if err := os.WriteFile(tmp, []byte("kind: B\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+		expectRuns(t, runs, 1)
+	}
+	stop()
+	assert.Equal(t, "error: the manifest is broken\r\nerror: the manifest is broken\r\n", stderr.String())
+}